@@ -0,0 +1,125 @@
+// Package assertwebp provides test assertions about webp files, for use in
+// other projects' test suites:
+//
+//	func TestAssets(t *testing.T) {
+//	    assertwebp.Valid(t, "public/logo.webp")
+//	    assertwebp.Animated(t, "public/spinner.webp", 2)
+//	    assertwebp.Dimensions(t, "public/hero.webp", 1920, 1080)
+//	}
+//
+// The assertions build the same report as the webp-validator verdict
+// command, in process with the webpvalidator package, so they see exactly
+// what the CLI and CI gates see without a binary to install. Like
+// testify's assert package, a failed assertion marks the test failed,
+// lets it continue and returns false.
+package assertwebp
+
+import (
+	"webpValidatorTest/report"
+	"webpValidatorTest/webpvalidator"
+)
+
+// TestingT is the part of testing.TB the assertions use.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Verdict returns the verdict report for path, failing t if the file
+// cannot be read or changes while it is validated. Invalid files are not
+// a failure here; check Report.Valid.
+func Verdict(t TestingT, path string) (report.Report, bool) {
+	t.Helper()
+
+	data, snapshot, err := webpvalidator.ReadWebpFile(path, nil)
+	if err != nil {
+		t.Errorf("assertwebp: %s: %v", path, err)
+		return report.Report{}, false
+	}
+	r := webpvalidator.Verdict(path, data)
+	if err := snapshot.Verify(); err != nil {
+		t.Errorf("assertwebp: %s: %v", path, err)
+		return report.Report{}, false
+	}
+	return r, true
+}
+
+// Valid asserts that path is a valid webp file.
+func Valid(t TestingT, path string) bool {
+	t.Helper()
+	r, ok := Verdict(t, path)
+	if !ok {
+		return false
+	}
+	if !r.Valid {
+		t.Errorf("expected %s to be a valid webp: %s", path, describeFailure(r))
+		return false
+	}
+	return true
+}
+
+// Invalid asserts that path is not a valid webp file but can be read.
+func Invalid(t TestingT, path string) bool {
+	t.Helper()
+	r, ok := Verdict(t, path)
+	if !ok {
+		return false
+	}
+	if r.Valid {
+		t.Errorf("expected %s to be an invalid webp, but it is a valid %dx%d image", path, r.Info.Width, r.Info.Height)
+		return false
+	}
+	return true
+}
+
+// Animated asserts that path is a valid animated webp with at least
+// minFrames frames.
+func Animated(t TestingT, path string, minFrames uint32) bool {
+	t.Helper()
+	r, ok := Verdict(t, path)
+	if !ok {
+		return false
+	}
+	switch {
+	case !r.Valid:
+		t.Errorf("expected %s to be an animated webp: %s", path, describeFailure(r))
+		return false
+	case !r.Info.IsAnimated:
+		t.Errorf("expected %s to be animated, but it is a still image", path)
+		return false
+	case r.Info.NumFrames < minFrames:
+		t.Errorf("expected %s to have at least %d frames, got %d", path, minFrames, r.Info.NumFrames)
+		return false
+	}
+	return true
+}
+
+// Dimensions asserts that path is a valid webp whose canvas is width by
+// height pixels.
+func Dimensions(t TestingT, path string, width, height uint32) bool {
+	t.Helper()
+	r, ok := Verdict(t, path)
+	if !ok {
+		return false
+	}
+	if !r.Valid {
+		t.Errorf("expected %s to be a valid %dx%d webp: %s", path, width, height, describeFailure(r))
+		return false
+	}
+	if r.Info.Width != width || r.Info.Height != height {
+		t.Errorf("expected %s to be %dx%d, got %dx%d", path, width, height, r.Info.Width, r.Info.Height)
+		return false
+	}
+	return true
+}
+
+// describeFailure names the first error finding, which locates the
+// problem more precisely than the decoder error.
+func describeFailure(r report.Report) string {
+	for _, f := range r.Findings {
+		if f.Severity == report.SeverityError {
+			return f.Message
+		}
+	}
+	return r.Error
+}
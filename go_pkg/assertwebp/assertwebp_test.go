@@ -0,0 +1,80 @@
+package assertwebp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"webpValidatorTest/webpvalidator"
+)
+
+// recordingT collects failures instead of failing the test.
+type recordingT struct{ errors []string }
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// fixturePath returns the path of the named standard fixture, created in
+// a directory private to the test.
+func fixturePath(t *testing.T, name string) string {
+	t.Helper()
+	if os.Getenv(webpvalidator.FixturesEnv) == "" {
+		t.Setenv(webpvalidator.FixturesEnv, t.TempDir())
+	}
+	path, err := webpvalidator.FixturePath(name)
+	require.NoError(t, err)
+	return path
+}
+
+func TestAssertions(t *testing.T) {
+	still := fixturePath(t, webpvalidator.FixtureStatic)
+	animated := fixturePath(t, webpvalidator.FixtureAnimated)
+	broken := fixturePath(t, webpvalidator.FixtureChunkOverflow)
+	missing := filepath.Join(t.TempDir(), "missing.webp")
+
+	rt := &recordingT{}
+	assert.True(t, Valid(rt, still))
+	assert.True(t, Dimensions(rt, still, 1, 1))
+	assert.True(t, Animated(rt, animated, 3))
+	assert.True(t, Invalid(rt, broken))
+	assert.Empty(t, rt.errors)
+
+	for _, failing := range []func() bool{
+		func() bool { return Valid(rt, broken) },
+		func() bool { return Invalid(rt, still) },
+		func() bool { return Animated(rt, still, 1) },
+		func() bool { return Animated(rt, animated, 4) },
+		func() bool { return Dimensions(rt, still, 1, 2) },
+		func() bool { return Valid(rt, missing) },
+	} {
+		assert.False(t, failing())
+	}
+	require.Len(t, rt.errors, 6)
+	assert.Equal(t, []string{
+		"expected " + broken + " to be a valid webp: VP8  chunk declares 4096 bytes, only 22 available",
+		"expected " + still + " to be an invalid webp, but it is a valid 1x1 image",
+		"expected " + still + " to be animated, but it is a still image",
+		"expected " + animated + " to have at least 4 frames, got 3",
+		"expected " + still + " to be 1x2, got 1x1",
+	}, rt.errors[:5])
+	// The rest of the message is the operating system's.
+	assert.Regexp(t, "^assertwebp: "+regexp.QuoteMeta(missing)+": failed to read file: ", rt.errors[5])
+}
+
+func TestVerdict(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureAnimated)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	rt := &recordingT{}
+	r, ok := Verdict(rt, path)
+	require.True(t, ok, rt.errors)
+	assert.Equal(t, webpvalidator.Verdict(path, data), r, "the same report as the verdict command")
+}
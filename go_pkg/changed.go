@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func runChanged(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("changed", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	since := flags.String("since", "", "git ref to diff against; if empty, read paths from stdin")
+	staged := flags.Bool("staged", false, "with -since, only consider staged changes (git diff --cached)")
+	all := flags.Bool("all", false, "check every changed webp, not only those in public/, assets/ and static/")
+	rate := rateFlag(flags)
+	format := formatFlag(flags, "text")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator changed [-since ref [-staged]] [-all] [-rate bytes/s] [-format name] [root]")
+		fmt.Fprintln(stderr, "\nvalidates only webp files added or modified since a git ref,")
+		fmt.Fprintln(stderr, "or the paths listed one per line on stdin (e.g. from git diff --name-only);")
+		fmt.Fprintln(stderr, "inside a git work tree, paths are relative to its top level, as git prints them")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) > 1 || (*staged && *since == "") {
+		flags.Usage()
+		return exitError
+	}
+
+	root := "."
+	if len(positional) == 1 {
+		root = positional[0]
+	}
+
+	var names []string
+	if *since != "" {
+		names, err = gitChangedFiles(root, *since, *staged)
+	} else {
+		names, err = readPathList(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "changed: %v\n", err)
+		return exitError
+	}
+
+	// git prints paths relative to the top of the work tree wherever it
+	// runs, so that is what names are relative to, unless root is not in
+	// a work tree at all.
+	base := gitTopLevel(root)
+	if base == "" {
+		base = root
+	}
+	paths, err := filterChanged(root, base, names, *all)
+	if err != nil {
+		fmt.Fprintf(stderr, "changed: %v\n", err)
+		return exitError
+	}
+
+	return writeFindings("changed", *format, lintFiles(root, paths, webpvalidator.NewThrottle(*rate)), stdout, stderr)
+}
+
+// gitChangedFiles lists files added or modified since ref in the work tree
+// containing root, relative to its top level.
+func gitChangedFiles(root, ref string, staged bool) ([]string, error) {
+	args := []string{"diff", "--name-only", "--diff-filter=AM", "-z"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, ref, "--")
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var names []string
+	for _, name := range bytes.Split(out, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// gitTopLevel returns the top directory of the git work tree containing
+// dir, or "" if dir is not in one or git is not installed.
+func gitTopLevel(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// readPathList reads one path per line, ignoring blank lines.
+func readPathList(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			names = append(names, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read paths: %w", err)
+	}
+	return names, nil
+}
+
+// filterChanged keeps the webp files named relative to base that still
+// exist below root, and returns them relative to root. Deleted files and
+// files outside root are dropped so a plain git diff --name-only can be
+// piped in. Unless all is set, only files inside an asset directory are
+// kept.
+func filterChanged(root, base string, names []string, all bool) ([]string, error) {
+	root, err := resolvedPath(root)
+	if err != nil {
+		return nil, err
+	}
+	if base, err = resolvedPath(base); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, name := range names {
+		rel, err := filepath.Rel(root, filepath.Join(base, filepath.FromSlash(name)))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if !isWebpName(rel) || (!all && !inAssetDir(rel)) {
+			continue
+		}
+
+		_, err = os.Stat(filepath.Join(root, rel))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		paths = append(paths, rel)
+	}
+	return paths, nil
+}
+
+// resolvedPath returns path made absolute with symlinks resolved, the form
+// git prints its top level in.
+func resolvedPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+// command is a CLI subcommand. It returns the process exit code.
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// commands maps subcommand names to their implementations.
+var commands = map[string]command{
+	"changed":     runChanged,
+	"conformance": runConformance,
+	"dump":        runDump,
+	"export":      runExport,
+	"fixtures":    runFixtures,
+	"inspect":     runInspect,
+	"lintrepo":    runLintRepo,
+	"verdict":     runVerdict,
+}
+
+// Exit codes shared by all subcommands.
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+// runCLI dispatches args (without the program name) to a subcommand.
+func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stderr)
+		return exitError
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+		printUsage(stderr)
+		return exitError
+	}
+
+	stop, err := webpvalidator.UseVerdictCacheEnv()
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+		return exitError
+	}
+	code := cmd(args[1:], stdin, stdout, stderr)
+	if err := stop(); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+	}
+	return code
+}
+
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: webp-validator <command> [flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+	fmt.Fprintln(w, "\nrun 'webp-validator <command> -h' for command flags")
+}
+
+// parseFlags parses flags that may appear before or after positional
+// arguments, so both "cmd -x file" and "cmd file -x" work, and returns the
+// positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// isTerminal reports whether w is a terminal rather than a file or pipe.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
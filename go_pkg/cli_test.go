@@ -0,0 +1,649 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"webpValidatorTest/report"
+	"webpValidatorTest/webpvalidator"
+)
+
+// runCLIForTest runs the CLI and returns its exit code, stdout and stderr.
+func runCLIForTest(args ...string) (int, string, string) {
+	return runCLIWithInput("", args...)
+}
+
+// runCLIWithInput is runCLIForTest with stdin.
+func runCLIWithInput(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeTree creates files below root. A file value that names a standard
+// fixture, such as webpvalidator.FixtureStatic, is written with the
+// fixture's contents, anything else is written verbatim.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+
+		data := []byte(content)
+		for _, fixture := range webpvalidator.Fixtures() {
+			if fixture.Name == content {
+				data = fixture.Data()
+			}
+		}
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+}
+
+// fixturePath returns the path of the named standard fixture, fetched into
+// a temporary directory unless $WEBP_VALIDATOR_FIXTURES is already set.
+func fixturePath(t *testing.T, name string) string {
+	t.Helper()
+	if os.Getenv(webpvalidator.FixturesEnv) == "" {
+		t.Setenv(webpvalidator.FixturesEnv, t.TempDir())
+	}
+	path, err := webpvalidator.FixturePath(name)
+	require.NoError(t, err)
+	return path
+}
+
+// fixtureData returns the contents of the named standard fixture.
+func fixtureData(t *testing.T, name string) []byte {
+	t.Helper()
+	for _, fixture := range webpvalidator.Fixtures() {
+		if fixture.Name == name {
+			return fixture.Data()
+		}
+	}
+	t.Fatalf("no fixture %s", name)
+	return nil
+}
+
+func TestCLIUnknownCommand(t *testing.T) {
+	code, _, stderr := runCLIForTest("frobnicate")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "unknown command: frobnicate")
+}
+
+func TestLintRepo(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"public/ok.webp":                  webpvalidator.FixtureStatic,
+		"public/fake.webp":                webpvalidator.FixtureNotWebp,
+		"src/ignored-outside-assets.webp": webpvalidator.FixtureNotWebp,
+		"assets/anim/.webp-policy.json":   `{"allow_animated": false, "max_frames": 2}`,
+		"assets/anim/banner.webp":         webpvalidator.FixtureAnimated,
+		"static/skip/.webp-policy.json":   `{"ignore": ["*.webp"]}`,
+		"static/skip/broken.webp":         webpvalidator.FixtureNotWebp,
+	})
+
+	code, stdout, _ := runCLIForTest("lintrepo", root)
+	assert.Equal(t, exitFindings, code)
+
+	lines := bytes.Split(bytes.TrimSpace([]byte(stdout)), []byte("\n"))
+	require.Len(t, lines, 3, "unexpected findings:\n%s", stdout)
+	assert.Contains(t, string(lines[0]), "assets/anim/banner.webp: animated webp not allowed by policy")
+	assert.Contains(t, string(lines[1]), "assets/anim/banner.webp: 3 frames exceeds policy max_frames 2")
+	assert.Contains(t, string(lines[2]), "public/fake.webp: invalid webp")
+
+	code, stdout, _ = runCLIForTest("lintrepo", "-all", root)
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stdout, "src/ignored-outside-assets.webp: invalid webp")
+
+	// Flags after the root are accepted too.
+	code, stdout, _ = runCLIForTest("lintrepo", root, "-all")
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stdout, "src/ignored-outside-assets.webp: invalid webp")
+}
+
+func TestLintRepoFileChanged(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"public/writing.webp": webpvalidator.FixtureStatic})
+	path := filepath.Join(root, "public", "writing.webp")
+
+	previous := webpvalidator.SetBackend(hookBackend{webpvalidator.NativeBackend, func() { require.NoError(t, os.Truncate(path, 10)) }})
+	defer webpvalidator.SetBackend(previous)
+
+	code, stdout, _ := runCLIForTest("lintrepo", root)
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stdout, "public/writing.webp: file changed during validation: size changed from")
+}
+
+// hookBackend runs hook before each validation, to simulate a writer
+// touching the file mid-validation.
+type hookBackend struct {
+	webpvalidator.Backend
+	hook func()
+}
+
+func (b hookBackend) Validate(data []byte) webpvalidator.WebpInfo {
+	b.hook()
+	return b.Backend.Validate(data)
+}
+
+func TestLintRepoFormats(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"public/ok.webp":   webpvalidator.FixtureStatic,
+		"public/fake.webp": webpvalidator.FixtureNotWebp,
+	})
+
+	code, stdout, _ := runCLIForTest("lintrepo", "-format", "jsonl", root)
+	assert.Equal(t, exitFindings, code)
+	var finding pathFinding
+	require.NoError(t, json.Unmarshal([]byte(stdout), &finding))
+	assert.Equal(t, "public/fake.webp", finding.Path)
+	assert.Contains(t, finding.Message, "invalid webp")
+
+	code, stdout, _ = runCLIForTest("lintrepo", "-format", "json", root)
+	assert.Equal(t, exitFindings, code)
+	var doc struct {
+		Command  string        `json:"command"`
+		Findings []pathFinding `json:"findings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "lintrepo", doc.Command)
+	assert.Equal(t, []pathFinding{finding}, doc.Findings)
+
+	code, _, stderr := runCLIForTest("lintrepo", "-format", "nosuchformat", root)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "unknown format \"nosuchformat\"")
+}
+
+func TestReportingCommandsFormat(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureNotWebp)
+	for _, command := range []string{"verdict", "inspect", "dump"} {
+		code, stdout, stderr := runCLIForTest(command, "-format", "jsonl", path)
+		assert.Equal(t, exitFindings, code, "%s: %s", command, stderr)
+		var finding pathFinding
+		require.NoError(t, json.Unmarshal([]byte(stdout), &finding), command)
+		assert.Equal(t, path, finding.Path, command)
+		assert.Equal(t, report.SeverityError, finding.Severity, command)
+	}
+
+	code, stdout, _ := runCLIForTest("verdict", "-format", "csv", fixturePath(t, webpvalidator.FixtureStatic))
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "path,severity,message,offset,length\n", stdout)
+
+	code, _, _ = runCLIForTest("dump", "-hex", "-format", "text", path)
+	assert.Equal(t, exitError, code, "-hex and -format are exclusive")
+}
+
+func TestWriteFindingsWarnings(t *testing.T) {
+	offset, length := uint64(12), uint64(8)
+	findings := []pathFinding{{"a.webp", report.Finding{Severity: report.SeverityWarning, Message: "odd", Offset: &offset, Length: &length}}}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitOK, writeFindings("verdict", "text", findings, &stdout, &stderr), "warnings alone do not fail")
+	assert.Equal(t, "a.webp: warning: odd\n", stdout.String())
+	assert.Equal(t, "1 finding(s)\n", stderr.String())
+
+	stdout.Reset()
+	findings = append(findings, errorFinding("b.webp", "broken"))
+	assert.Equal(t, exitFindings, writeFindings("verdict", "csv", findings, &stdout, &stderr))
+	assert.Equal(t, "path,severity,message,offset,length\na.webp,warning,odd,12,8\nb.webp,error,broken,,\n", stdout.String())
+}
+
+func TestExportFormats(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureStatic)
+
+	code, stdout, stderr := runCLIForTest("export", "-format", "json", path)
+	assert.Equal(t, exitOK, code, stderr)
+	var doc struct {
+		Command string          `json:"command"`
+		Columns []report.Column `json:"columns"`
+		Rows    []fileFeatures  `json:"rows"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, "export", doc.Command)
+	assert.Equal(t, featureColumns, doc.Columns)
+	require.Len(t, doc.Rows, 1)
+	assert.True(t, doc.Rows[0].Valid)
+
+	code, stdout, stderr = runCLIForTest("export", "-format", "text", path)
+	assert.Equal(t, exitOK, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"path", "size", "valid"}, strings.Fields(lines[0])[:3])
+
+	code, _, stderr = runCLIForTest("export", "-format", "nosuchformat", path)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "unknown format \"nosuchformat\"")
+}
+
+func TestLintRepoFormatterPlugin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("plugin test needs sh")
+	}
+	bin := t.TempDir()
+	writeTree(t, bin, map[string]string{
+		"webp-validator-format-ticket": "#!/bin/sh\necho \"ticket for $1\"\ncat\n",
+		"webp-validator-format-broken": "#!/bin/sh\necho 'cannot format' >&2\nexit 3\n",
+	})
+	for _, name := range []string{"ticket", "broken"} {
+		require.NoError(t, os.Chmod(filepath.Join(bin, "webp-validator-format-"+name), 0o755))
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"public/fake.webp": webpvalidator.FixtureNotWebp})
+
+	code, stdout, _ := runCLIForTest("lintrepo", "-format", "ticket", root)
+	assert.Equal(t, exitFindings, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 4, stdout)
+	assert.Equal(t, "ticket for lintrepo", lines[0])
+	assert.JSONEq(t, `{"type":"begin","protocol":1,"command":"lintrepo"}`, lines[1])
+	assert.Contains(t, lines[2], `"type":"finding","path":"public/fake.webp"`)
+	assert.JSONEq(t, `{"type":"end","findings":1}`, lines[3])
+
+	code, _, stderr := runCLIForTest("lintrepo", "-format", "broken", root)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "cannot format")
+	assert.Contains(t, stderr, "exit status 3")
+
+	code, stdout, stderr = runCLIForTest("export", "-format", "ticket", filepath.Join(root, "public"))
+	assert.Equal(t, exitOK, code, stderr)
+	lines = strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 5, stdout)
+	assert.Equal(t, "ticket for export", lines[0])
+	assert.Contains(t, lines[2], `"type":"columns","columns":[{"name":"path","type":"string"}`)
+	assert.Contains(t, lines[3], `"type":"row","values":["`)
+	assert.JSONEq(t, `{"type":"end","findings":0,"rows":1}`, lines[4])
+}
+
+func TestLintRepoClean(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"web/public/img/ok.webp": webpvalidator.FixtureStatic,
+	})
+
+	code, stdout, _ := runCLIForTest("lintrepo", root)
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+}
+
+func TestLintRepoInvalidPolicy(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"public/.webp-policy.json": `{"max_width": "wide"}`,
+		"public/ok.webp":           webpvalidator.FixtureStatic,
+	})
+
+	code, stdout, _ := runCLIForTest("lintrepo", root)
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stdout, "public/ok.webp: invalid policy file")
+
+	// A misspelled limit must not silently disable its check.
+	writeTree(t, root, map[string]string{"public/.webp-policy.json": `{"max_widht": 10}`})
+	code, stdout, _ = runCLIForTest("lintrepo", root)
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stdout, "public/ok.webp: invalid policy file "+filepath.Join(root, "public", ".webp-policy.json")+`: unknown key "max_widht"`)
+}
+
+func TestChangedFromStdin(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"public/ok.webp":   webpvalidator.FixtureStatic,
+		"public/fake.webp": webpvalidator.FixtureNotWebp,
+		"src/other.webp":   webpvalidator.FixtureNotWebp,
+	})
+
+	stdin := "public/ok.webp\npublic/fake.webp\npublic/deleted.webp\nsrc/other.webp\nREADME.md\n"
+	code, stdout, _ := runCLIWithInput(stdin, "changed", root)
+	assert.Equal(t, exitFindings, code)
+	assert.Equal(t, "public/fake.webp", strings.SplitN(strings.TrimSpace(stdout), ":", 2)[0])
+	assert.Equal(t, 1, strings.Count(stdout, "\n"), "only the changed asset should be reported:\n%s", stdout)
+}
+
+func TestChangedSinceGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, out)
+	}
+
+	writeTree(t, root, map[string]string{
+		"public/old-broken.webp": webpvalidator.FixtureNotWebp,
+		"public/gone.webp":       webpvalidator.FixtureStatic,
+	})
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	writeTree(t, root, map[string]string{
+		"public/new-broken.webp": webpvalidator.FixtureNotWebp,
+		"public/new-ok.webp":     webpvalidator.FixtureAnimated,
+	})
+	require.NoError(t, os.Remove(filepath.Join(root, "public/gone.webp")))
+	git("add", "-A")
+
+	code, stdout, stderr := runCLIForTest("changed", "-since", "HEAD", "-staged", root)
+	assert.Equal(t, exitFindings, code, stderr)
+	assert.Contains(t, stdout, "public/new-broken.webp: invalid webp")
+	assert.NotContains(t, stdout, "old-broken", "unchanged files should not be checked")
+
+	// Flags after the root are accepted too.
+	code, stdout, stderr = runCLIForTest("changed", root, "-since", "HEAD", "-staged")
+	assert.Equal(t, exitFindings, code, stderr)
+	assert.Contains(t, stdout, "public/new-broken.webp: invalid webp")
+}
+
+func TestChangedInSubdirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	top := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = top
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, out)
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	writeTree(t, top, map[string]string{
+		"web/public/fake.webp":   webpvalidator.FixtureNotWebp,
+		"other/public/fake.webp": webpvalidator.FixtureNotWebp,
+	})
+	git("add", "-A")
+
+	// git diff --name-only prints paths from the top of the work tree.
+	stdin := "web/public/fake.webp\nother/public/fake.webp\n"
+	code, stdout, _ := runCLIWithInput(stdin, "changed", filepath.Join(top, "web"))
+	assert.Equal(t, exitFindings, code)
+	assert.Equal(t, "public/fake.webp", strings.SplitN(strings.TrimSpace(stdout), ":", 2)[0])
+	assert.Equal(t, 1, strings.Count(stdout, "\n"), "files outside root should be dropped:\n%s", stdout)
+
+	code, stdout, stderr := runCLIForTest("changed", "-since", "HEAD", "-staged", filepath.Join(top, "web"))
+	assert.Equal(t, exitFindings, code, stderr)
+	assert.Equal(t, "public/fake.webp", strings.SplitN(strings.TrimSpace(stdout), ":", 2)[0])
+	assert.Equal(t, 1, strings.Count(stdout, "\n"), stdout)
+}
+
+func TestVerdictValid(t *testing.T) {
+	code, stdout, _ := runCLIForTest("verdict", fixturePath(t, webpvalidator.FixtureAlpha))
+	assert.Equal(t, exitOK, code)
+
+	var v report.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &v))
+	assert.Equal(t, report.Version, v.Version)
+	assert.True(t, v.Valid)
+	assert.Empty(t, v.Findings)
+	require.NotEmpty(t, v.Chunks)
+	assert.Equal(t, report.Chunk{FourCC: "VP8X", Offset: 12, Length: 18, PayloadOffset: 20, PayloadLength: 10}, v.Chunks[0])
+
+	last := v.Chunks[len(v.Chunks)-1]
+	assert.Equal(t, uint64(v.Size), last.Offset+last.Length, "chunks should cover the file")
+	assert.Empty(t, v.Frames)
+}
+
+func TestVerdictAnimated(t *testing.T) {
+	code, stdout, _ := runCLIForTest("verdict", fixturePath(t, webpvalidator.FixtureAnimated))
+	assert.Equal(t, exitOK, code)
+
+	v, err := report.Parse([]byte(stdout))
+	require.NoError(t, err)
+	require.Len(t, v.Frames, int(v.Info.NumFrames))
+	require.NotEmpty(t, v.Frames)
+	require.GreaterOrEqual(t, len(v.Chunks), 3)
+	assert.Equal(t, v.Chunks[2].Offset, v.Frames[0].Offset, "first frame should be the first ANMF chunk")
+
+	var frames, nested int
+	require.NoError(t, report.Walk(v, report.VisitorFunc(func(n report.Node) error {
+		switch n := n.(type) {
+		case *report.Frame:
+			frames++
+		case *report.Chunk:
+			if n.Depth > 0 {
+				nested++
+			}
+		}
+		return nil
+	})))
+	assert.Equal(t, len(v.Frames), frames)
+	assert.Greater(t, nested, 0)
+}
+
+func TestVerdictTruncated(t *testing.T) {
+	data := fixtureData(t, webpvalidator.FixtureAlpha)
+	path := filepath.Join(t.TempDir(), "truncated.webp")
+	require.NoError(t, os.WriteFile(path, data[:len(data)-10], 0o644))
+
+	code, stdout, _ := runCLIForTest("verdict", path, "-compact")
+	assert.Equal(t, exitFindings, code)
+	assert.Equal(t, 1, strings.Count(stdout, "\n"), "compact verdict should be one line, with the flag after the file")
+
+	var v report.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &v))
+	assert.False(t, v.Valid)
+	assert.True(t, v.Partial)
+	require.Len(t, v.Findings, 2)
+	assert.Equal(t, uint64(4), *v.Findings[0].Offset, "riff size field should be flagged")
+	assert.Equal(t, uint64(4), *v.Findings[0].Length)
+
+	require.NotEmpty(t, v.Chunks)
+	last := v.Chunks[len(v.Chunks)-1]
+	assert.Equal(t, last.Offset+last.Length, *v.Findings[1].Offset, "truncated chunk should start after the last complete one")
+	assert.Equal(t, uint64(len(data)-10), *v.Findings[1].Offset+*v.Findings[1].Length, "truncated chunk should run to end of file")
+}
+
+func TestVerdictFake(t *testing.T) {
+	code, stdout, _ := runCLIForTest("verdict", fixturePath(t, webpvalidator.FixtureNotWebp))
+	assert.Equal(t, exitFindings, code)
+
+	var v report.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &v))
+	assert.False(t, v.Valid)
+	assert.Empty(t, v.Chunks)
+	require.Len(t, v.Findings, 1)
+	assert.Equal(t, "missing RIFF signature", v.Findings[0].Message)
+	assert.Equal(t, uint64(0), *v.Findings[0].Offset)
+}
+
+func TestInspect(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureAnimated)
+	code, stdout, _ := runCLIForTest("inspect", path)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, path+": valid")
+	assert.Contains(t, stdout, "\n  VP8X  @12  18 bytes\n")
+	assert.Contains(t, stdout, "\n  ANMF  @44  46 bytes\n    ", "frame sub-chunks should be nested")
+	assert.Contains(t, stdout, "\nframes:\n  #1  @44  ")
+	assert.Contains(t, stdout, "\nfindings:\n  (none)\n")
+}
+
+func TestInspectTUI(t *testing.T) {
+	// Flags after the file name are accepted too.
+	code, stdout, _ := runCLIWithInput("f\n2\nj\ne\nq\n", "inspect", fixturePath(t, webpvalidator.FixtureAnimated), "--tui")
+	assert.Equal(t, exitOK, code)
+	assert.NotContains(t, stdout, "\x1b[2J", "screen should not be cleared when not a terminal")
+	assert.Contains(t, stdout, "> ")
+	assert.Contains(t, stdout, "frames (", "frames pane should be shown")
+	assert.Contains(t, stdout, "frame #3 in ANMF chunk", "cursor should move to frame 3")
+	assert.Contains(t, stdout, "findings (0):")
+}
+
+func TestInspectKeys(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureAnimated)
+	view := newInspectView(path, fixtureData(t, webpvalidator.FixtureAnimated))
+
+	// Arrow keys move, Tab and ← switch panes, digits and Enter jump.
+	var out bytes.Buffer
+	view.interactKeys(strings.NewReader("f\x1b[B\x1b[B"), crlfWriter{&out})
+	assert.Contains(t, out.String(), "frame #3 in ANMF chunk")
+	assert.NotContains(t, strings.ReplaceAll(out.String(), "\r\n", ""), "\n", "every newline has a carriage return")
+
+	out.Reset()
+	view.interactKeys(strings.NewReader("\t\x1b[Dg1\x7f2\r\x1b"), &out)
+	assert.Equal(t, paneFrames, view.pane)
+	assert.Equal(t, 1, view.cursor[paneFrames])
+	assert.Contains(t, out.String(), "q quit > 1\x1b[H", "typed digits are echoed")
+	assert.Contains(t, out.String(), "q quit > 2\x1b[H", "backspace removes a digit")
+}
+
+func TestAnnotateCoversFile(t *testing.T) {
+	for _, name := range []string{webpvalidator.FixtureAlpha, webpvalidator.FixtureAnimated, webpvalidator.FixtureNotWebp} {
+		data := fixtureData(t, name)
+
+		for _, size := range []int{len(data), len(data) / 2} {
+			ranges := annotate(data[:size], webpvalidator.InspectWebp(data[:size]))
+			var offset uint64
+			for _, r := range ranges {
+				require.Equal(t, offset, r.Offset, "%s[:%d]: gap or overlap before %q", name, size, r.Label)
+				require.NotZero(t, r.Length, "%s[:%d]: empty range %q", name, size, r.Label)
+				offset += r.Length
+			}
+			assert.Equal(t, uint64(size), offset, "%s[:%d]: ranges should cover the file", name, size)
+		}
+	}
+}
+
+func TestDumpHex(t *testing.T) {
+	code, stdout, _ := runCLIForTest("dump", "--hex", fixturePath(t, webpvalidator.FixtureAlpha))
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "00000000  52 49 46 46")
+	assert.Contains(t, stdout, "RIFF signature\n")
+	assert.Contains(t, stdout, "  flags: alpha\n")
+	assert.Contains(t, stdout, "canvas width - 1: 0\n")
+
+	// The ICC profile is long enough to be elided.
+	path := fixturePath(t, webpvalidator.FixtureMetadata)
+	_, stdout, _ = runCLIForTest("dump", "-hex", path)
+	assert.Contains(t, stdout, "more bytes\n", "long ranges should be elided")
+	_, full, _ := runCLIForTest("dump", "-hex", "-full", path)
+	assert.NotContains(t, full, "more bytes\n", "-full should dump every byte")
+}
+
+func TestVP8XFlagNames(t *testing.T) {
+	assert.Equal(t, "none", vp8xFlagNames(0))
+	assert.Equal(t, "fragments", vp8xFlagNames(0x01))
+	assert.Equal(t, "icc, alpha, exif, xmp, animation, fragments", vp8xFlagNames(0x3f))
+}
+
+func TestAnnotatePadding(t *testing.T) {
+	data := fixtureData(t, webpvalidator.FixtureAlpha)
+	data = append(data, 'J', 'U', 'N', 'K', 3, 0, 0, 0, 'a', 'b', 'c', 0)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+
+	ranges := annotate(data, webpvalidator.InspectWebp(data))
+	require.GreaterOrEqual(t, len(ranges), 2)
+	assert.Equal(t, byteRange{uint64(len(data) - 4), 3, "payload", 1}, ranges[len(ranges)-2])
+	assert.Equal(t, byteRange{uint64(len(data) - 1), 1, "padding", 0}, ranges[len(ranges)-1])
+}
+
+func TestDumpTruncated(t *testing.T) {
+	data := fixtureData(t, webpvalidator.FixtureAlpha)
+	path := filepath.Join(t.TempDir(), "truncated.webp")
+	require.NoError(t, os.WriteFile(path, data[:len(data)-10], 0o644))
+
+	code, stdout, _ := runCLIForTest("dump", path)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "unparsed: VP8  chunk declares")
+}
+
+func TestExportCSV(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"static.webp":  webpvalidator.FixtureStatic,
+		"dynamic.webp": webpvalidator.FixtureAnimated,
+		"fake.webp":    webpvalidator.FixtureNotWebp,
+	})
+	code, stdout, stderr := runCLIForTest("export", root)
+	assert.Equal(t, exitOK, code, stderr)
+
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4, "header plus one row per image")
+	for i, column := range featureColumns {
+		assert.Equal(t, column.Name, records[0][i])
+	}
+
+	rows := map[string]map[string]string{}
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, column := range featureColumns {
+			row[column.Name] = record[i]
+		}
+		rows[filepath.Base(row["path"])] = row
+	}
+
+	dynamic := rows["dynamic.webp"]
+	assert.Equal(t, "true", dynamic["is_animated"])
+	assert.Equal(t, dynamic["num_frames"], dynamic["chunks_vp8l"], "each frame has one VP8L chunk")
+	assert.NotEqual(t, "0", dynamic["duration_total_ms"])
+	assert.Equal(t, "false", rows["fake.webp"]["valid"])
+	assert.Equal(t, "0.0000", rows["static.webp"]["duration_mean_ms"])
+}
+
+func TestExportJSONL(t *testing.T) {
+	code, stdout, _ := runCLIForTest("export", "-format", "jsonl", fixturePath(t, webpvalidator.FixtureAlpha))
+	assert.Equal(t, exitOK, code)
+
+	var f fileFeatures
+	require.NoError(t, json.Unmarshal([]byte(stdout), &f))
+	assert.True(t, f.Valid)
+	assert.True(t, f.HasAlpha)
+	assert.Equal(t, 1, f.ChunksALPH)
+	assert.Greater(t, f.Entropy, 0.0)
+	assert.LessOrEqual(t, f.Entropy, 8.0)
+	assert.Greater(t, f.BitsPerPixel, 0.0)
+}
+
+func TestExportWorkersPreserveOrder(t *testing.T) {
+	args := []string{"export", "-format", "jsonl", "-workers", "3"}
+	for i := 0; i < 5; i++ {
+		args = append(args, fixturePath(t, webpvalidator.FixtureAnimated), fixturePath(t, webpvalidator.FixtureStatic), fixturePath(t, webpvalidator.FixtureNotWebp))
+	}
+
+	code, stdout, stderr := runCLIForTest(args...)
+	assert.Equal(t, exitOK, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 15)
+	for i, line := range lines {
+		assert.Contains(t, line, args[5+i], "line %d out of order", i)
+	}
+}
+
+func TestFeatureColumnsMatchJSON(t *testing.T) {
+	data, err := json.Marshal(fileFeatures{})
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+
+	assert.Len(t, fields, len(featureColumns))
+	for _, column := range featureColumns {
+		assert.Contains(t, fields, column.Name)
+	}
+
+	// Table rows encode exactly like the struct, so jsonl output did not
+	// change when export moved to the formatters.
+	f := extractFeatures("animated.webp", fixtureData(t, webpvalidator.FixtureAnimated))
+	want, err := json.Marshal(f)
+	require.NoError(t, err)
+	row, err := rowObject(featureColumns, f.values())
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(row))
+}
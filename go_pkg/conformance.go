@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func runConformance(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("conformance", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	jsonOutput := flags.Bool("json", false, "print the results as JSON instead of a matrix")
+	format := formatFlag(flags, "")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator conformance [-json | -format name] [list]")
+		fmt.Fprintln(stderr, "\nruns the conformance vectors against the native library, bypassing any verdict cache, and prints a certification matrix")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) > 1 || (len(positional) == 1 && positional[0] != "list") || (*jsonOutput && *format != "") {
+		flags.Usage()
+		return exitError
+	}
+
+	vectors := webpvalidator.ConformanceVectors()
+	if len(positional) == 1 {
+		for _, v := range vectors {
+			fmt.Fprintf(stdout, "%-24s %-8s %s\n", v.Name, v.Class, v.Description)
+		}
+		return exitOK
+	}
+
+	supported, err := webpvalidator.NativeBackend.SupportedFeatures()
+	if err != nil {
+		fmt.Fprintf(stderr, "conformance: %v\n", err)
+		return exitError
+	}
+	results := make([]webpvalidator.ConformanceResult, 0, len(vectors))
+	passed := 0
+	for _, v := range vectors {
+		result := webpvalidator.RunConformance(v)
+		if result.Pass {
+			passed++
+		}
+		results = append(results, result)
+	}
+
+	if *format != "" {
+		return writeFindings("conformance", *format, conformanceFindings(results), stdout, stderr)
+	}
+	if *jsonOutput {
+		err = json.NewEncoder(stdout).Encode(struct {
+			Suite     int                               `json:"suite"`
+			Platform  string                            `json:"platform"`
+			Features  string                            `json:"features"`
+			Certified bool                              `json:"certified"`
+			Results   []webpvalidator.ConformanceResult `json:"results"`
+		}{webpvalidator.ConformanceSuiteVersion, runtime.GOOS + "/" + runtime.GOARCH, supported.String(), passed == len(results), results})
+	} else {
+		err = printConformanceMatrix(stdout, supported, results, passed)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "conformance: %v\n", err)
+		return exitError
+	}
+
+	if passed != len(results) {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// conformanceFindings returns an error finding for every failed check,
+// located in its vector.
+func conformanceFindings(results []webpvalidator.ConformanceResult) []pathFinding {
+	var findings []pathFinding
+	for _, result := range results {
+		for _, check := range result.Checks {
+			if !check.Pass {
+				findings = append(findings, errorFinding(result.Vector,
+					fmt.Sprintf("%s: want %s, got %s", check.Name, check.Want, check.Got)))
+			}
+		}
+	}
+	return findings
+}
+
+func printConformanceMatrix(w io.Writer, supported webpvalidator.Features, results []webpvalidator.ConformanceResult, passed int) error {
+	fmt.Fprintf(w, "conformance suite %d on %s/%s, library features %s\n\n",
+		webpvalidator.ConformanceSuiteVersion, runtime.GOOS, runtime.GOARCH, supported)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "vector\tclass\t%s\n", strings.Join(webpvalidator.ConformanceCheckNames(), "\t"))
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%s", result.Vector, result.Class)
+		for _, check := range result.Checks {
+			status := "pass"
+			if !check.Pass {
+				status = "FAIL"
+			}
+			fmt.Fprintf(table, "\t%s", status)
+		}
+		fmt.Fprintln(table)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		for _, check := range result.Checks {
+			if !check.Pass {
+				fmt.Fprintf(w, "\n%s %s:\n  want %s\n  got  %s\n", result.Vector, check.Name, check.Want, check.Got)
+			}
+		}
+	}
+
+	verdict := "certified"
+	if passed != len(results) {
+		verdict = "NOT certified"
+	}
+	_, err := fmt.Fprintf(w, "\n%d/%d vectors pass: %s\n", passed, len(results), verdict)
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func TestConformanceCommand(t *testing.T) {
+	code, stdout, stderr := runCLIForTest("conformance")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "library features "+webpvalidator.KnownFeatures.String())
+	assert.Contains(t, stdout, "16/16 vectors pass: certified")
+	assert.NotContains(t, stdout, "FAIL")
+
+	code, stdout, stderr = runCLIForTest("conformance", "-json")
+	require.Equal(t, exitOK, code, stderr)
+	var out struct {
+		Certified bool                              `json:"certified"`
+		Results   []webpvalidator.ConformanceResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	assert.True(t, out.Certified)
+	assert.Len(t, out.Results, len(webpvalidator.ConformanceVectors()))
+
+	code, stdout, _ = runCLIForTest("conformance", "list")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, len(webpvalidator.ConformanceVectors()), strings.Count(stdout, "\n"))
+
+	code, _, _ = runCLIForTest("conformance", "bogus")
+	assert.Equal(t, exitError, code)
+}
+
+func TestConformanceCommandReportsFailures(t *testing.T) {
+	v := webpvalidator.ConformanceVectors()[0]
+	v.Expect.Width = 2
+	var out strings.Builder
+	require.NoError(t, printConformanceMatrix(&out, webpvalidator.KnownFeatures, []webpvalidator.ConformanceResult{webpvalidator.RunConformance(v)}, 0))
+	assert.Contains(t, out.String(), "FAIL")
+	assert.Contains(t, out.String(), "static.webp info:\n  want 2x1, 0 frames\n  got  1x1, 0 frames\n")
+	assert.Contains(t, out.String(), "0/1 vectors pass: NOT certified")
+}
+
+func TestConformanceBypassesBackend(t *testing.T) {
+	// Certification is about the library, not whatever
+	// webpvalidator.SetBackend or a verdict cache put in front of it.
+	previous := webpvalidator.SetBackend(everythingValidBackend{webpvalidator.NativeBackend})
+	defer webpvalidator.SetBackend(previous)
+	t.Setenv(webpvalidator.VerdictCacheEnv, filepath.Join(t.TempDir(), "verdicts"))
+
+	before := webpvalidator.Stats()
+	code, stdout, stderr := runCLIForTest("conformance")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "16/16 vectors pass: certified")
+	assert.Equal(t, before, webpvalidator.Stats(), "conformance does not go through ValidateWebp")
+}
+
+// everythingValidBackend claims every input is a valid 1x1 image.
+type everythingValidBackend struct{ webpvalidator.Backend }
+
+func (everythingValidBackend) Validate([]byte) webpvalidator.WebpInfo {
+	return webpvalidator.WebpInfo{IsValid: true, Width: 1, Height: 1}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+// dumpPreviewBytes is how many bytes of a long range dump shows unless
+// -full is given.
+const dumpPreviewBytes = 32
+
+// byteRange labels a span of the file with what it means.
+type byteRange struct {
+	Offset uint64
+	Length uint64
+	Label  string
+	// Depth is the nesting level, for indenting labels.
+	Depth int
+}
+
+// fieldWriter appends consecutive labeled fields starting at an offset.
+type fieldWriter struct {
+	ranges []byteRange
+	offset uint64
+	depth  int
+}
+
+func (fw *fieldWriter) add(length uint64, format string, args ...any) {
+	fw.ranges = append(fw.ranges, byteRange{fw.offset, length, fmt.Sprintf(format, args...), fw.depth})
+	fw.offset += length
+}
+
+// annotate labels every byte of data: the file header, each chunk header,
+// the fields of known chunk payloads, and anything the walk could not
+// parse. Ranges are returned in file order and do not overlap.
+func annotate(data []byte, inspection webpvalidator.WebpInspection) []byteRange {
+	fw := &fieldWriter{}
+	if len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		fw.add(4, "RIFF signature")
+		fw.add(4, "RIFF size: %d", binary.LittleEndian.Uint32(data[4:8]))
+		fw.add(4, "WEBP signature")
+	}
+
+	// Chunks are in file order with ANMF sub-chunks following their frame,
+	// so a parent's trailing padding is emitted once its children are done.
+	var parents []webpvalidator.WebpChunk
+	closeParents := func(until uint64) {
+		for len(parents) > 0 && parents[len(parents)-1].Offset+parents[len(parents)-1].Length() <= until {
+			parent := parents[len(parents)-1]
+			parents = parents[:len(parents)-1]
+			fw.depth = int(parent.Depth)
+			annotatePadding(fw, parent)
+		}
+	}
+
+	for _, c := range inspection.Chunks {
+		closeParents(c.Offset)
+		fw.offset = c.Offset
+		fw.depth = int(c.Depth)
+		fw.add(4, "%s chunk", c.FourCC)
+		fw.add(4, "%s size: %d", c.FourCC, c.Size)
+		fw.depth++
+		annotatePayload(fw, c, data[c.PayloadOffset():c.PayloadOffset()+uint64(c.Size)])
+		if c.FourCC == "ANMF" && c.Size >= 16 {
+			parents = append(parents, c)
+			continue
+		}
+		fw.depth--
+		annotatePadding(fw, c)
+	}
+	closeParents(uint64(len(data)))
+
+	return fillGaps(fw.ranges, uint64(len(data)), inspection.Findings)
+}
+
+func annotatePadding(fw *fieldWriter, c webpvalidator.WebpChunk) {
+	fw.offset = c.PayloadOffset() + uint64(c.Size)
+	if c.Size&1 == 1 {
+		fw.add(1, "padding")
+	}
+}
+
+// annotatePayload labels the fixed fields of known chunk types. p is the
+// chunk payload.
+func annotatePayload(fw *fieldWriter, c webpvalidator.WebpChunk, p []byte) {
+	size := uint64(len(p))
+	switch {
+	case c.FourCC == "VP8X" && size >= 10:
+		fw.add(1, "flags: %s", vp8xFlagNames(p[0]))
+		fw.add(3, "reserved")
+		fw.add(3, "canvas width - 1: %d", webpvalidator.ReadUint24(p[4:7]))
+		fw.add(3, "canvas height - 1: %d", webpvalidator.ReadUint24(p[7:10]))
+		size -= 10
+	case c.FourCC == "ANIM" && size >= 6:
+		fw.add(4, "background color (BGRA): #%02x%02x%02x%02x", p[2], p[1], p[0], p[3])
+		fw.add(2, "loop count: %d", binary.LittleEndian.Uint16(p[4:6]))
+		size -= 6
+	case c.FourCC == "ANMF" && size >= 16:
+		fw.add(3, "frame x / 2: %d", webpvalidator.ReadUint24(p[0:3]))
+		fw.add(3, "frame y / 2: %d", webpvalidator.ReadUint24(p[3:6]))
+		fw.add(3, "frame width - 1: %d", webpvalidator.ReadUint24(p[6:9]))
+		fw.add(3, "frame height - 1: %d", webpvalidator.ReadUint24(p[9:12]))
+		fw.add(3, "frame duration: %dms", webpvalidator.ReadUint24(p[12:15]))
+		fw.add(1, "frame flags: %s", anmfFlagNames(p[15]))
+		// The rest of the payload is sub-chunks, labeled on their own.
+		return
+	case c.FourCC == "ALPH" && size >= 1:
+		fw.add(1, "alpha header: compression %d, filtering %d, preprocessing %d", p[0]&0x03, (p[0]>>2)&0x03, (p[0]>>4)&0x03)
+		size--
+		if size > 0 {
+			fw.add(size, "alpha bitstream")
+		}
+		return
+	case c.FourCC == "VP8 " && size >= 10:
+		fw.add(3, "frame tag: key frame %v, first partition %d bytes", p[0]&0x01 == 0, webpvalidator.ReadUint24(p[0:3])>>5)
+		fw.add(3, "start code")
+		fw.add(2, "width: %d (scale %d)", binary.LittleEndian.Uint16(p[6:8])&0x3fff, p[7]>>6)
+		fw.add(2, "height: %d (scale %d)", binary.LittleEndian.Uint16(p[8:10])&0x3fff, p[9]>>6)
+		size -= 10
+	case c.FourCC == "VP8L" && size >= 5:
+		bits := binary.LittleEndian.Uint32(p[1:5])
+		fw.add(1, "VP8L signature")
+		fw.add(4, "width - 1: %d, height - 1: %d, alpha hint %v, version %d",
+			bits&0x3fff, (bits>>14)&0x3fff, bits&(1<<28) != 0, bits>>29)
+		size -= 5
+	}
+
+	if size > 0 {
+		fw.add(size, "%s", payloadLabel(c.FourCC))
+	}
+}
+
+func payloadLabel(fourcc string) string {
+	switch fourcc {
+	case "VP8 ":
+		return "VP8 bitstream"
+	case "VP8L":
+		return "VP8L bitstream"
+	case "ICCP":
+		return "ICC profile"
+	case "EXIF":
+		return "EXIF metadata"
+	case "XMP ":
+		return "XMP metadata"
+	default:
+		return "payload"
+	}
+}
+
+// vp8xFlagNames names the set VP8X flag bits, highest first. Bit 0x01 is
+// reserved in the current spec but marked fragmented images, which the
+// native library still reports as FeatureFragments.
+func vp8xFlagNames(flags byte) string {
+	var names []string
+	for _, flag := range []struct {
+		bit  byte
+		name string
+	}{{0x20, "icc"}, {0x10, "alpha"}, {0x08, "exif"}, {0x04, "xmp"}, {0x02, "animation"}, {0x01, "fragments"}} {
+		if flags&flag.bit != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+func anmfFlagNames(flags byte) string {
+	blend := "blend"
+	if flags&0x02 != 0 {
+		blend = "no blend"
+	}
+	dispose := "keep"
+	if flags&0x01 != 0 {
+		dispose = "dispose to background"
+	}
+	return blend + ", " + dispose
+}
+
+// fillGaps labels every byte not covered by ranges as unparsed, naming
+// the finding that starts there, if any.
+func fillGaps(ranges []byteRange, size uint64, findings []webpvalidator.WebpFinding) []byteRange {
+	var filled []byteRange
+	var offset uint64
+	gap := func(end uint64) {
+		if end <= offset {
+			return
+		}
+		label := "unparsed"
+		for _, f := range findings {
+			if f.Offset >= offset && f.Offset < end {
+				label = "unparsed: " + f.Message
+				break
+			}
+		}
+		filled = append(filled, byteRange{offset, end - offset, label, 0})
+	}
+
+	for _, r := range ranges {
+		if r.Offset+r.Length > size {
+			r.Length = size - min(r.Offset, size)
+		}
+		if r.Length == 0 {
+			continue
+		}
+		gap(r.Offset)
+		filled = append(filled, r)
+		offset = r.Offset + r.Length
+	}
+	gap(size)
+	return filled
+}
+
+func runDump(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	hex := flags.Bool("hex", false, "include an annotated hexdump of every range")
+	full := flags.Bool("full", false, fmt.Sprintf("with -hex, dump ranges longer than %d bytes in full", dumpPreviewBytes))
+	format := formatFlag(flags, "")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator dump [-hex [-full] | -format name] file.webp")
+		fmt.Fprintln(stderr, "\nlabels every byte range of the container with its chunk/field meaning")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 1 || (*hex && *format != "") {
+		flags.Usage()
+		return exitError
+	}
+
+	data, _, err := webpvalidator.ReadWebpFile(positional[0], nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "dump: %v\n", err)
+		return exitError
+	}
+	if *format != "" {
+		return writeFindings("dump", *format, reportFindings(webpvalidator.Verdict(positional[0], data)), stdout, stderr)
+	}
+
+	ranges := annotate(data, webpvalidator.InspectWebp(data))
+	for _, r := range ranges {
+		label := strings.Repeat("  ", r.Depth) + r.Label
+		if !*hex {
+			fmt.Fprintf(stdout, "%08x  %10d  %s\n", r.Offset, r.Length, label)
+			continue
+		}
+		dumpRange(stdout, data, r, label, *full)
+	}
+	return exitOK
+}
+
+// dumpRange writes the bytes of r, 16 per row, with label on the first row.
+func dumpRange(w io.Writer, data []byte, r byteRange, label string, full bool) {
+	end := r.Offset + r.Length
+	if !full && r.Length > dumpPreviewBytes {
+		end = r.Offset + dumpPreviewBytes
+	}
+
+	for row := r.Offset; row < end; row += 16 {
+		b := data[row:min(row+16, end)]
+		ascii := make([]byte, len(b))
+		for i, c := range b {
+			ascii[i] = '.'
+			if c >= 0x20 && c < 0x7f {
+				ascii[i] = c
+			}
+		}
+		fmt.Fprintf(w, "%08x  %-47s  %-16s  %s\n", row, fmt.Sprintf("% x", b), ascii, label)
+		label = ""
+	}
+	if end < r.Offset+r.Length {
+		fmt.Fprintf(w, "%08x  ... %d more bytes\n", end, r.Offset+r.Length-end)
+	}
+}
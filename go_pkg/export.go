@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func runExport(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := formatFlag(flags, "csv")
+	rate := rateFlag(flags)
+	opts := poolFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator export [-format name] [-rate bytes/s] [-workers n] [-cpuset list] path...")
+		fmt.Fprintln(stderr, "\nexports one feature vector per webp file; directories are scanned recursively")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) == 0 {
+		flags.Usage()
+		return exitError
+	}
+
+	var paths []string
+	for _, arg := range positional {
+		expanded, err := expandPath(arg)
+		if err != nil {
+			fmt.Fprintf(stderr, "export: %v\n", err)
+			return exitError
+		}
+		paths = append(paths, expanded...)
+	}
+
+	pool, err := webpvalidator.NewPool(*opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "export: %v\n", err)
+		return exitError
+	}
+	defer pool.Close()
+
+	formatter, err := newFormatter(*format, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "export: %v\n", err)
+		return exitError
+	}
+	writeErr := formatter.Begin("export")
+	if writeErr == nil {
+		writeErr = formatter.Columns(featureColumns)
+	}
+
+	// Files are processed concurrently but written in input order: each
+	// file gets a result channel, queued in order for the writer below.
+	type result struct {
+		features fileFeatures
+		err      error
+	}
+	queue := make(chan chan result, 2*pool.Workers())
+	throttle := webpvalidator.NewThrottle(*rate)
+	go func() {
+		defer close(queue)
+		for _, path := range paths {
+			done := make(chan result, 1)
+			queue <- done
+			pool.Go(func() {
+				data, snapshot, err := webpvalidator.ReadWebpFile(path, throttle)
+				if err != nil {
+					done <- result{err: err}
+					return
+				}
+				features := extractFeatures(filepath.ToSlash(path), data)
+				if err := snapshot.Verify(); err != nil {
+					done <- result{err: fmt.Errorf("%s: %w", path, err)}
+					return
+				}
+				done <- result{features: features}
+			})
+		}
+	}()
+
+	for done := range queue {
+		r := <-done
+		if writeErr != nil {
+			continue
+		}
+		if r.err != nil {
+			fmt.Fprintf(stderr, "export: %v\n", r.err)
+			continue
+		}
+		writeErr = formatter.Row(r.features.values())
+	}
+	if endErr := formatter.End(); writeErr == nil {
+		writeErr = endErr
+	}
+	if writeErr != nil {
+		fmt.Fprintf(stderr, "export: %v\n", writeErr)
+		return exitError
+	}
+	return exitOK
+}
+
+// expandPath returns path itself if it is a file, or every webp file below
+// it if it is a directory.
+func expandPath(path string) ([]string, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return []string{path}, nil
+	}
+
+	rels, err := findAssets(path, true)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(rels))
+	for i, rel := range rels {
+		paths[i] = filepath.Join(path, rel)
+	}
+	return paths, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"webpValidatorTest/report"
+	"webpValidatorTest/webpvalidator"
+)
+
+// fileFeatures is the per-file feature vector exported for training
+// abuse-detection and quality models.
+type fileFeatures struct {
+	Path    string `json:"path"`
+	Size    int    `json:"size"`
+	Valid   bool   `json:"valid"`
+	Partial bool   `json:"partial"`
+	Error   string `json:"error"`
+
+	Width      uint32  `json:"width"`
+	Height     uint32  `json:"height"`
+	Aspect     float64 `json:"aspect"`
+	HasAlpha   bool    `json:"has_alpha"`
+	IsAnimated bool    `json:"is_animated"`
+	NumFrames  uint32  `json:"num_frames"`
+	LoopCount  int     `json:"loop_count"`
+
+	// Frame durations in milliseconds, from the ANMF headers.
+	DurationTotal uint64  `json:"duration_total_ms"`
+	DurationMin   uint32  `json:"duration_min_ms"`
+	DurationMax   uint32  `json:"duration_max_ms"`
+	DurationMean  float64 `json:"duration_mean_ms"`
+
+	// Entropy is the Shannon entropy of the file bytes, in bits per byte.
+	Entropy float64 `json:"entropy"`
+	// BitsPerPixel is bitstream bits per canvas pixel per frame, a coarse
+	// quality/compression estimate.
+	BitsPerPixel float64 `json:"bits_per_pixel"`
+
+	NumChunks     int    `json:"num_chunks"`
+	ChunksVP8     int    `json:"chunks_vp8"`
+	ChunksVP8L    int    `json:"chunks_vp8l"`
+	ChunksALPH    int    `json:"chunks_alph"`
+	ChunksOther   int    `json:"chunks_other"`
+	BytesVP8      uint64 `json:"bytes_vp8"`
+	BytesVP8L     uint64 `json:"bytes_vp8l"`
+	BytesALPH     uint64 `json:"bytes_alph"`
+	BytesMetadata uint64 `json:"bytes_metadata"`
+	NumErrors     int    `json:"num_errors"`
+	NumWarnings   int    `json:"num_warnings"`
+
+	// Content hints.
+	Lossless bool `json:"lossless"`
+	HasICC   bool `json:"has_icc"`
+	HasEXIF  bool `json:"has_exif"`
+	HasXMP   bool `json:"has_xmp"`
+}
+
+// featureColumns is the export table schema: one column per fileFeatures
+// field, in order, named by its json tag. fileFeatures.values must list
+// the fields in the same order.
+var featureColumns = structColumns(reflect.TypeFor[fileFeatures]())
+
+// structColumns returns a column for each field of the struct type t,
+// named by its json tag and typed by its kind.
+func structColumns(t reflect.Type) []report.Column {
+	columns := make([]report.Column, t.NumField())
+	for i := range columns {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			panic(fmt.Sprintf("%s.%s has no json name", t.Name(), field.Name))
+		}
+		columns[i].Name = name
+		switch field.Type.Kind() {
+		case reflect.String:
+			columns[i].Type = report.ColumnString
+		case reflect.Bool:
+			columns[i].Type = report.ColumnBool
+		case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint64:
+			columns[i].Type = report.ColumnInt
+		case reflect.Float64:
+			columns[i].Type = report.ColumnFloat
+		default:
+			panic(fmt.Sprintf("%s.%s: unsupported column kind %s", t.Name(), field.Name, field.Type.Kind()))
+		}
+	}
+	return columns
+}
+
+// extractFeatures computes the feature vector of one file.
+func extractFeatures(path string, data []byte) fileFeatures {
+	info := webpvalidator.ValidateWebp(data)
+	inspection := webpvalidator.InspectWebp(data)
+
+	f := fileFeatures{
+		Path:       path,
+		Size:       len(data),
+		Valid:      info.IsValid,
+		Partial:    info.Partial,
+		Error:      info.Error,
+		Width:      info.Width,
+		Height:     info.Height,
+		HasAlpha:   info.HasAlpha,
+		IsAnimated: info.IsAnimated,
+		NumFrames:  info.NumFrames,
+		Entropy:    byteEntropy(data),
+		NumChunks:  len(inspection.Chunks),
+	}
+	if info.Height > 0 {
+		f.Aspect = float64(info.Width) / float64(info.Height)
+	}
+
+	for _, c := range inspection.Chunks {
+		switch c.FourCC {
+		case "VP8 ":
+			f.ChunksVP8++
+			f.BytesVP8 += uint64(c.Size)
+		case "VP8L":
+			f.ChunksVP8L++
+			f.BytesVP8L += uint64(c.Size)
+			f.Lossless = true
+		case "ALPH":
+			f.ChunksALPH++
+			f.BytesALPH += uint64(c.Size)
+		case "ICCP", "EXIF", "XMP ":
+			f.ChunksOther++
+			f.BytesMetadata += uint64(c.Size)
+			f.HasICC = f.HasICC || c.FourCC == "ICCP"
+			f.HasEXIF = f.HasEXIF || c.FourCC == "EXIF"
+			f.HasXMP = f.HasXMP || c.FourCC == "XMP "
+		case "ANIM":
+			if c.Size >= 6 {
+				f.LoopCount = int(binary.LittleEndian.Uint16(data[c.PayloadOffset()+4:]))
+			}
+		case "VP8X", "ANMF":
+		default:
+			f.ChunksOther++
+		}
+	}
+
+	for _, finding := range inspection.Findings {
+		if finding.Warning {
+			f.NumWarnings++
+		} else {
+			f.NumErrors++
+		}
+	}
+
+	frames := inspection.Frames(data)
+	for i, frame := range frames {
+		f.DurationTotal += uint64(frame.Duration)
+		if i == 0 || frame.Duration < f.DurationMin {
+			f.DurationMin = frame.Duration
+		}
+		f.DurationMax = max(f.DurationMax, frame.Duration)
+	}
+	if len(frames) > 0 {
+		f.DurationMean = float64(f.DurationTotal) / float64(len(frames))
+	}
+
+	if pixels := float64(info.Width) * float64(info.Height) * float64(max(len(frames), 1)); pixels > 0 {
+		f.BitsPerPixel = float64(f.BytesVP8+f.BytesVP8L+f.BytesALPH) * 8 / pixels
+	}
+
+	return f
+}
+
+// byteEntropy returns the Shannon entropy of data in bits per byte.
+func byteEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	var entropy float64
+	n := float64(len(data))
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / n
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// values returns the export table row for f, in featureColumns order.
+func (f fileFeatures) values() []any {
+	i := func(v int) any { return int64(v) }
+	u := func(v uint64) any { return int64(v) }
+
+	return []any{
+		f.Path, i(f.Size), f.Valid, f.Partial, f.Error,
+		u(uint64(f.Width)), u(uint64(f.Height)), f.Aspect, f.HasAlpha, f.IsAnimated, u(uint64(f.NumFrames)), i(f.LoopCount),
+		u(f.DurationTotal), u(uint64(f.DurationMin)), u(uint64(f.DurationMax)), f.DurationMean,
+		f.Entropy, f.BitsPerPixel,
+		i(f.NumChunks), i(f.ChunksVP8), i(f.ChunksVP8L), i(f.ChunksALPH), i(f.ChunksOther),
+		u(f.BytesVP8), u(f.BytesVP8L), u(f.BytesALPH), u(f.BytesMetadata), i(f.NumErrors), i(f.NumWarnings),
+		f.Lossless, f.HasICC, f.HasEXIF, f.HasXMP,
+	}
+}
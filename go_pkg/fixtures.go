@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func runFixtures(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fixtures", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", "", fmt.Sprintf("fixture directory (default $%s or the user cache)", webpvalidator.FixturesEnv))
+	link := flags.String("link", "", "with fetch, also create a symlink at this path pointing to the fixture directory")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator fixtures [-dir path] [-link path] fetch|list")
+		fmt.Fprintln(stderr, "\ncreates the standard fixture set (static, animated, alpha, metadata, corrupted variants)")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 1 || (positional[0] != "fetch" && positional[0] != "list") {
+		flags.Usage()
+		return exitError
+	}
+
+	if positional[0] == "list" {
+		for _, fixture := range webpvalidator.Fixtures() {
+			verdict := "invalid"
+			if fixture.Valid {
+				verdict = "valid"
+			}
+			fmt.Fprintf(stdout, "%-20s %-8s %s\n", fixture.Name, verdict, fixture.Description)
+		}
+		return exitOK
+	}
+
+	if *dir == "" {
+		if *dir, err = webpvalidator.FixtureDir(); err != nil {
+			fmt.Fprintf(stderr, "fixtures: %v\n", err)
+			return exitError
+		}
+	}
+	if err := webpvalidator.FetchFixtures(*dir); err != nil {
+		fmt.Fprintf(stderr, "fixtures: %v\n", err)
+		return exitError
+	}
+	if *link != "" {
+		if err := linkFixtureDir(*dir, *link); err != nil {
+			fmt.Fprintf(stderr, "fixtures: %v\n", err)
+			return exitError
+		}
+	}
+	fmt.Fprintln(stdout, *dir)
+	return exitOK
+}
+
+// linkFixtureDir points a symlink at link to dir, replacing an existing
+// symlink but never a real file or directory.
+func linkFixtureDir(dir, link string) error {
+	target, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if stat, err := os.Lstat(link); err == nil {
+		if stat.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("cannot link %s: exists and is not a symlink", link)
+		}
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(target, link)
+}
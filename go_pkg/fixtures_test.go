@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func TestFixturesCommand(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	link := filepath.Join(t.TempDir(), "testdata")
+
+	code, stdout, stderr := runCLIForTest("fixtures", "-dir", dir, "-link", link, "fetch")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, dir+"\n", stdout)
+	for _, fixture := range webpvalidator.Fixtures() {
+		assert.FileExists(t, filepath.Join(link, fixture.Name))
+	}
+
+	// Fetching again is a no-op and re-points the link.
+	code, _, stderr = runCLIForTest("fixtures", "fetch", "-dir", dir, "-link", link)
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, stdout, _ = runCLIForTest("fixtures", "list")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "chunk-overflow.webp  invalid")
+
+	code, _, _ = runCLIForTest("fixtures", "remove")
+	assert.Equal(t, exitError, code)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+// parseByteRate parses a rate such as "1048576", "512K" or "20M" (binary
+// multiples, optional trailing "B" or "iB"). An empty string or "0" means
+// unlimited.
+func parseByteRate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	upper := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "B"), "I")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(upper, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(upper, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(upper, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		upper = upper[:len(upper)-1]
+	}
+
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte rate %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// rateFlag registers the -rate flag shared by the batch scanning commands.
+func rateFlag(flags *flag.FlagSet) *int64 {
+	rate := new(int64)
+	flags.Func("rate", "limit disk reads to this many bytes/s, e.g. 512K or 20M (default unlimited)", func(s string) error {
+		n, err := parseByteRate(s)
+		*rate = n
+		return err
+	})
+	return rate
+}
+
+// poolFlags registers the -workers and -cpuset flags of commands that
+// validate on a Pool.
+func poolFlags(flags *flag.FlagSet) *webpvalidator.Options {
+	opts := &webpvalidator.Options{}
+	flags.IntVar(&opts.Workers, "workers", 0, "concurrent validations (default one per cpu)")
+	flags.Func("cpuset", "pin workers to these cpus, e.g. 0-3,8 (default unpinned)", func(s string) error {
+		cpus, err := webpvalidator.ParseCPUSet(s)
+		opts.CPUSet = cpus
+		return err
+	})
+	return opts
+}
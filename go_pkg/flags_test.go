@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func TestParseByteRate(t *testing.T) {
+	for input, expected := range map[string]int64{
+		"":      0,
+		"0":     0,
+		"1000":  1000,
+		"512K":  512 << 10,
+		"512kb": 512 << 10,
+		"20M":   20 << 20,
+		"1GiB":  1 << 30,
+	} {
+		n, err := parseByteRate(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, n, input)
+	}
+
+	for _, input := range []string{"fast", "-1", "10T"} {
+		_, err := parseByteRate(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestLintRepoRate(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"public/ok.webp": webpvalidator.FixtureStatic,
+	})
+
+	code, _, _ := runCLIForTest("lintrepo", "-rate", "64M", root)
+	assert.Equal(t, exitOK, code)
+
+	code, _, stderr := runCLIForTest("lintrepo", "-rate", "fast", root)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, `invalid byte rate "fast"`)
+}
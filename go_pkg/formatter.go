@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"webpValidatorTest/report"
+)
+
+// formatterPluginPrefix is prepended to a -format name that is not built
+// in to find a plugin executable on PATH.
+const formatterPluginPrefix = "webp-validator-format-"
+
+// formatterProtocolVersion is sent to plugins in the begin record and
+// bumped on incompatible changes to the records.
+const formatterProtocolVersion = 1
+
+// formatterFactory creates a formatter writing to stdout.
+type formatterFactory func(stdout io.Writer) report.TableFormatter
+
+// formatters are the built-in formats. Any other name is looked up as a
+// plugin executable (see pluginFormatter).
+var formatters = map[string]formatterFactory{
+	"text":    func(w io.Writer) report.TableFormatter { return &textFormatter{w: w} },
+	"jsonl":   func(w io.Writer) report.TableFormatter { return &jsonlFormatter{w: w} },
+	"json":    func(w io.Writer) report.TableFormatter { return &jsonFormatter{w: w} },
+	"csv":     func(w io.Writer) report.TableFormatter { return &csvFormatter{w: csv.NewWriter(w)} },
+	"parquet": func(w io.Writer) report.TableFormatter { return newParquetFormatter(w) },
+}
+
+// formatFlag registers the -format flag shared by the reporting commands.
+// If def is empty the command keeps its own output unless -format is
+// given.
+func formatFlag(flags *flag.FlagSet, def string) *string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	usage := fmt.Sprintf("output format: %s, or NAME to run %sNAME from PATH",
+		strings.Join(names, ", "), formatterPluginPrefix)
+	if def == "" {
+		usage += "; by default the command's own output"
+	}
+	return flags.String("format", def, usage)
+}
+
+// newFormatter returns the built-in formatter called name, or a plugin
+// found on PATH. Plugin diagnostics go to stderr.
+func newFormatter(name string, stdout, stderr io.Writer) (report.TableFormatter, error) {
+	if factory, ok := formatters[name]; ok {
+		return factory(stdout), nil
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid format name %q", name)
+	}
+
+	path, err := exec.LookPath(formatterPluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown format %q: not built in and no %s%s on PATH", name, formatterPluginPrefix, name)
+	}
+	return &pluginFormatter{path: path, stdout: stdout, stderr: stderr}, nil
+}
+
+// pathFinding is a finding located in a file, or another named subject
+// such as a conformance vector.
+type pathFinding struct {
+	Path string `json:"path"`
+	report.Finding
+}
+
+// errorFinding returns an error finding for path without a byte range.
+func errorFinding(path, message string) pathFinding {
+	return pathFinding{path, report.Finding{Severity: report.SeverityError, Message: message}}
+}
+
+// reportFindings returns the findings of a verdict, located in its file.
+func reportFindings(v report.Report) []pathFinding {
+	findings := make([]pathFinding, len(v.Findings))
+	for i, f := range v.Findings {
+		findings[i] = pathFinding{v.Path, f}
+	}
+	return findings
+}
+
+func (f pathFinding) String() string {
+	if f.Severity == report.SeverityWarning {
+		return fmt.Sprintf("%s: warning: %s", f.Path, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", f.Path, f.Message)
+}
+
+// writeFindings formats findings for command and returns the exit code,
+// which is exitFindings only if there are errors; warnings are reported
+// but do not fail the command.
+func writeFindings(command, format string, findings []pathFinding, stdout, stderr io.Writer) int {
+	formatter, err := newFormatter(format, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", command, err)
+		return exitError
+	}
+
+	err = formatter.Begin(command)
+	errors := 0
+	for _, f := range findings {
+		if err != nil {
+			break
+		}
+		if f.Severity == report.SeverityError {
+			errors++
+		}
+		err = formatter.Finding(f.Path, f.Finding)
+	}
+	if endErr := formatter.End(); err == nil {
+		err = endErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", command, err)
+		return exitError
+	}
+
+	if len(findings) > 0 {
+		fmt.Fprintf(stderr, "%d finding(s)\n", len(findings))
+	}
+	if errors > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// findingColumns are the columns table formats such as csv write findings
+// in.
+var findingColumns = []report.Column{
+	{Name: "path", Type: report.ColumnString},
+	{Name: "severity", Type: report.ColumnString},
+	{Name: "message", Type: report.ColumnString},
+	{Name: "offset", Type: report.ColumnInt},
+	{Name: "length", Type: report.ColumnInt},
+}
+
+// findingValues returns f as a row of findingColumns, with nil for a
+// missing byte range.
+func findingValues(path string, f report.Finding) []any {
+	values := []any{path, f.Severity, f.Message, nil, nil}
+	if f.Offset != nil {
+		values[3] = int64(*f.Offset)
+	}
+	if f.Length != nil {
+		values[4] = int64(*f.Length)
+	}
+	return values
+}
+
+// formatValue renders a table value as text. Floats get four decimals,
+// which is plenty for the exported features and keeps columns aligned.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// rowObject encodes a table row as a JSON object with the column names as
+// keys, in column order.
+func rowObject(columns []report.Column, values []any) (json.RawMessage, error) {
+	if len(values) != len(columns) {
+		return nil, fmt.Errorf("row has %d values for %d columns", len(values), len(columns))
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(column.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// textFormatter prints "path: message" lines, for humans and pre-commit
+// hook output, and tables as aligned columns.
+type textFormatter struct {
+	w     io.Writer
+	table *tabwriter.Writer
+}
+
+func (f *textFormatter) Begin(string) error { return nil }
+
+func (f *textFormatter) Finding(path string, finding report.Finding) error {
+	_, err := fmt.Fprintln(f.w, pathFinding{path, finding})
+	return err
+}
+
+func (f *textFormatter) Columns(columns []report.Column) error {
+	f.table = tabwriter.NewWriter(f.w, 0, 0, 2, ' ', 0)
+	names := make([]any, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return f.Row(names)
+}
+
+func (f *textFormatter) Row(values []any) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = formatValue(v)
+	}
+	_, err := fmt.Fprintln(f.table, strings.Join(cells, "\t"))
+	return err
+}
+
+func (f *textFormatter) End() error {
+	if f.table == nil {
+		return nil
+	}
+	return f.table.Flush()
+}
+
+// jsonlFormatter prints one JSON object per finding or table row.
+type jsonlFormatter struct {
+	w       io.Writer
+	columns []report.Column
+}
+
+func (f *jsonlFormatter) Begin(string) error { return nil }
+
+func (f *jsonlFormatter) Finding(path string, finding report.Finding) error {
+	return json.NewEncoder(f.w).Encode(pathFinding{path, finding})
+}
+
+func (f *jsonlFormatter) Columns(columns []report.Column) error {
+	f.columns = columns
+	return nil
+}
+
+func (f *jsonlFormatter) Row(values []any) error {
+	row, err := rowObject(f.columns, values)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f.w, "%s\n", row)
+	return err
+}
+
+func (f *jsonlFormatter) End() error { return nil }
+
+// jsonFormatter prints a single document once all findings or rows are
+// known.
+type jsonFormatter struct {
+	w        io.Writer
+	command  string
+	findings []pathFinding
+	columns  []report.Column
+	rows     []json.RawMessage
+}
+
+func (f *jsonFormatter) Begin(command string) error {
+	f.command = command
+	f.findings = []pathFinding{}
+	return nil
+}
+
+func (f *jsonFormatter) Finding(path string, finding report.Finding) error {
+	f.findings = append(f.findings, pathFinding{path, finding})
+	return nil
+}
+
+func (f *jsonFormatter) Columns(columns []report.Column) error {
+	f.columns = columns
+	f.rows = []json.RawMessage{}
+	return nil
+}
+
+func (f *jsonFormatter) Row(values []any) error {
+	row, err := rowObject(f.columns, values)
+	if err != nil {
+		return err
+	}
+	f.rows = append(f.rows, row)
+	return nil
+}
+
+func (f *jsonFormatter) End() error {
+	enc := json.NewEncoder(f.w)
+	enc.SetIndent("", "  ")
+	if f.columns != nil {
+		return enc.Encode(struct {
+			Command string            `json:"command"`
+			Columns []report.Column   `json:"columns"`
+			Rows    []json.RawMessage `json:"rows"`
+		}{f.command, f.columns, f.rows})
+	}
+	return enc.Encode(struct {
+		Command  string        `json:"command"`
+		Findings []pathFinding `json:"findings"`
+	}{f.command, f.findings})
+}
+
+// csvFormatter prints a table with a header row. Findings are written as
+// a table of findingColumns.
+type csvFormatter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (f *csvFormatter) Begin(string) error { return nil }
+
+func (f *csvFormatter) Columns(columns []report.Column) error {
+	f.header = true
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return f.w.Write(names)
+}
+
+func (f *csvFormatter) Row(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+	}
+	return f.w.Write(record)
+}
+
+func (f *csvFormatter) Finding(path string, finding report.Finding) error {
+	if !f.header {
+		if err := f.Columns(findingColumns); err != nil {
+			return err
+		}
+	}
+	return f.Row(findingValues(path, finding))
+}
+
+func (f *csvFormatter) End() error {
+	if !f.header {
+		if err := f.Columns(findingColumns); err != nil {
+			return err
+		}
+	}
+	f.w.Flush()
+	return f.w.Error()
+}
+
+// pluginRecord is one line of the plugin protocol.
+type pluginRecord struct {
+	Type     string          `json:"type"`
+	Protocol int             `json:"protocol,omitempty"`
+	Command  string          `json:"command,omitempty"`
+	Path     string          `json:"path,omitempty"`
+	Severity string          `json:"severity,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	Offset   *uint64         `json:"offset,omitempty"`
+	Length   *uint64         `json:"length,omitempty"`
+	Columns  []report.Column `json:"columns,omitempty"`
+	Values   []any           `json:"values,omitempty"`
+	Findings *int            `json:"findings,omitempty"`
+	Rows     *int            `json:"rows,omitempty"`
+}
+
+// pluginFormatter runs an external formatter. The plugin is started with
+// the command name as its only argument and reads JSON lines on stdin:
+//
+//	{"type":"begin","protocol":1,"command":"lintrepo"}
+//	{"type":"finding","path":"public/a.webp","severity":"error","message":"invalid webp: ..."}
+//	{"type":"end","findings":1}
+//
+// Findings carry "offset" and "length" when they have a byte range. A
+// command writing a table, such as export, sends a columns record and
+// then one row record per row instead of findings:
+//
+//	{"type":"columns","columns":[{"name":"path","type":"string"},...]}
+//	{"type":"row","values":["public/a.webp",...]}
+//	{"type":"end","findings":0,"rows":1}
+//
+// Its stdout and stderr are passed through, and a non-zero exit status is
+// an error. Unknown record types and fields must be ignored, so records
+// can grow without a protocol bump.
+type pluginFormatter struct {
+	path   string
+	stdout io.Writer
+	stderr io.Writer
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	count int
+	// rows counts table rows, once Columns has been called.
+	rows *int
+	// writeErr is the first failed write to the plugin. It only matters if
+	// the plugin then fails too: a plugin that exits successfully without
+	// reading all of its input closed the pipe by choice.
+	writeErr error
+}
+
+func (f *pluginFormatter) Begin(command string) error {
+	f.cmd = exec.Command(f.path, command)
+	f.cmd.Stdout = f.stdout
+	f.cmd.Stderr = f.stderr
+	stdin, err := f.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := f.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start formatter plugin: %w", err)
+	}
+
+	f.stdin = stdin
+	f.buf = bufio.NewWriter(stdin)
+	f.enc = json.NewEncoder(f.buf)
+	f.send(pluginRecord{Type: "begin", Protocol: formatterProtocolVersion, Command: command})
+	return nil
+}
+
+func (f *pluginFormatter) Finding(path string, finding report.Finding) error {
+	f.count++
+	f.send(pluginRecord{Type: "finding", Path: path, Severity: finding.Severity,
+		Message: finding.Message, Offset: finding.Offset, Length: finding.Length})
+	return nil
+}
+
+func (f *pluginFormatter) Columns(columns []report.Column) error {
+	f.rows = new(int)
+	f.send(pluginRecord{Type: "columns", Columns: columns})
+	return nil
+}
+
+func (f *pluginFormatter) Row(values []any) error {
+	*f.rows++
+	f.send(pluginRecord{Type: "row", Values: values})
+	return nil
+}
+
+func (f *pluginFormatter) send(record pluginRecord) {
+	if f.writeErr == nil {
+		f.writeErr = f.enc.Encode(record)
+	}
+}
+
+func (f *pluginFormatter) End() error {
+	if f.cmd == nil || f.cmd.Process == nil {
+		return nil
+	}
+
+	f.send(pluginRecord{Type: "end", Findings: &f.count, Rows: f.rows})
+	if f.writeErr == nil {
+		f.writeErr = f.buf.Flush()
+	}
+	f.stdin.Close()
+	if err := f.cmd.Wait(); err != nil {
+		if f.writeErr != nil {
+			return fmt.Errorf("formatter plugin %s: %w (after write error: %v)", f.path, err, f.writeErr)
+		}
+		return fmt.Errorf("formatter plugin %s: %w", f.path, err)
+	}
+	return nil
+}
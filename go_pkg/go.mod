@@ -4,10 +4,19 @@ go 1.25
 
 require golang.org/x/image v0.34.0
 
-require github.com/stretchr/testify v1.11.1
+require (
+	github.com/parquet-go/parquet-go v0.25.1
+	github.com/stretchr/testify v1.11.1
+	golang.org/x/term v0.37.0
+)
 
 require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/term"
+	"webpValidatorTest/webpvalidator"
+)
+
+// inspectPane is one of the lists the interactive inspector can browse.
+type inspectPane int
+
+const (
+	paneChunks inspectPane = iota
+	paneFrames
+	paneFindings
+)
+
+var paneNames = [...]string{"chunks", "frames", "findings"}
+
+// inspectWindow is how many list rows the interactive inspector shows
+// around the cursor.
+const inspectWindow = 15
+
+// inspectView holds everything known about one file and the browsing
+// state of the interactive inspector.
+type inspectView struct {
+	path       string
+	data       []byte
+	info       webpvalidator.WebpInfo
+	inspection webpvalidator.WebpInspection
+	frames     []webpvalidator.WebpFrame
+
+	pane   inspectPane
+	cursor [len(paneNames)]int
+
+	// preview is how the frames pane draws the selected frame. Frames are
+	// decoded on first use.
+	preview   previewMode
+	decoded   []webpvalidator.DecodedFrame
+	decodeErr error
+
+	// keys is set when reading single key presses rather than lines;
+	// pending holds the digits typed so far of an item number.
+	keys    bool
+	pending string
+}
+
+func newInspectView(path string, data []byte) *inspectView {
+	inspection := webpvalidator.InspectWebp(data)
+	return &inspectView{
+		path:       path,
+		data:       data,
+		info:       webpvalidator.ValidateWebp(data),
+		inspection: inspection,
+		frames:     inspection.Frames(data),
+	}
+}
+
+func runInspect(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	tui := flags.Bool("tui", false, "browse chunks, frames and findings interactively")
+	preview := flags.String("preview", string(previewAuto), "with -tui, how to draw frame previews: auto, kitty, sixel, text or none")
+	format := formatFlag(flags, "")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator inspect [-tui [-preview mode] | -format name] file.webp")
+		fmt.Fprintln(stderr, "\nprints the chunk tree, frame list and findings of a file")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 1 || (*tui && *format != "") {
+		flags.Usage()
+		return exitError
+	}
+	mode, err := parsePreviewMode(*preview)
+	if err != nil {
+		fmt.Fprintf(stderr, "inspect: %v\n", err)
+		return exitError
+	}
+
+	data, _, err := webpvalidator.ReadWebpFile(positional[0], nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "inspect: %v\n", err)
+		return exitError
+	}
+	if *format != "" {
+		return writeFindings("inspect", *format, reportFindings(webpvalidator.Verdict(positional[0], data)), stdout, stderr)
+	}
+
+	view := newInspectView(positional[0], data)
+	if *tui {
+		terminal := isTerminal(stdout)
+		view.preview = resolvePreview(mode, terminal, os.Getenv)
+		if in, ok := stdin.(*os.File); ok && terminal && term.IsTerminal(int(in.Fd())) {
+			if err := view.interactRaw(in, stdout); err != nil {
+				fmt.Fprintf(stderr, "inspect: %v\n", err)
+				return exitError
+			}
+		} else {
+			view.interact(stdin, stdout, terminal)
+		}
+	} else {
+		view.print(stdout)
+	}
+
+	if !view.info.IsValid {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// print writes every pane in full.
+func (v *inspectView) print(w io.Writer) {
+	v.printHeader(w)
+	for pane := range paneNames {
+		fmt.Fprintf(w, "\n%s:\n", paneNames[pane])
+		rows := v.rows(inspectPane(pane))
+		if len(rows) == 0 {
+			fmt.Fprintln(w, "  (none)")
+		}
+		for _, row := range rows {
+			fmt.Fprintf(w, "  %s\n", row)
+		}
+	}
+}
+
+func (v *inspectView) printHeader(w io.Writer) {
+	status := "valid"
+	if !v.info.IsValid {
+		status = "invalid"
+		if v.info.Partial {
+			status = "invalid (partial metadata)"
+		}
+	}
+	fmt.Fprintf(w, "%s: %s, %d bytes\n", v.path, status, len(v.data))
+	if v.info.IsValid || v.info.Partial {
+		fmt.Fprintf(w, "  %dx%d, alpha: %v, animated: %v, frames: %d\n",
+			v.info.Width, v.info.Height, v.info.HasAlpha, v.info.IsAnimated, v.info.NumFrames)
+	}
+	if v.info.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", v.info.Error)
+	}
+}
+
+// rows returns one line per item in pane.
+func (v *inspectView) rows(pane inspectPane) []string {
+	var rows []string
+	switch pane {
+	case paneChunks:
+		for _, c := range v.inspection.Chunks {
+			indent := strings.Repeat("  ", int(c.Depth))
+			rows = append(rows, fmt.Sprintf("%s%-4s  @%d  %d bytes", indent, c.FourCC, c.Offset, c.Length()))
+		}
+	case paneFrames:
+		for i, f := range v.frames {
+			rows = append(rows, fmt.Sprintf("#%d  @%d  %dx%d at (%d,%d)  %dms",
+				i+1, f.Chunk.Offset, f.Width, f.Height, f.X, f.Y, f.Duration))
+		}
+	case paneFindings:
+		for _, f := range v.inspection.Findings {
+			severity := "error"
+			if f.Warning {
+				severity = "warning"
+			}
+			rows = append(rows, fmt.Sprintf("%s  @%d+%d  %s", severity, f.Offset, f.Length, f.Message))
+		}
+	}
+	return rows
+}
+
+// detail describes the item under the cursor of the current pane.
+func (v *inspectView) detail() []string {
+	i := v.cursor[v.pane]
+	switch v.pane {
+	case paneChunks:
+		if i >= len(v.inspection.Chunks) {
+			return nil
+		}
+		c := v.inspection.Chunks[i]
+		return []string{
+			fmt.Sprintf("chunk %q, depth %d", c.FourCC, c.Depth),
+			fmt.Sprintf("header:  %d..%d", c.Offset, c.PayloadOffset()),
+			fmt.Sprintf("payload: %d..%d (%d bytes)", c.PayloadOffset(), c.PayloadOffset()+uint64(c.Size), c.Size),
+			"bytes:   " + v.hexPreview(c.PayloadOffset(), uint64(c.Size)),
+		}
+	case paneFrames:
+		if i >= len(v.frames) {
+			return nil
+		}
+		f := v.frames[i]
+		return []string{
+			fmt.Sprintf("frame #%d in ANMF chunk @%d", i+1, f.Chunk.Offset),
+			fmt.Sprintf("size:     %dx%d", f.Width, f.Height),
+			fmt.Sprintf("position: (%d,%d)", f.X, f.Y),
+			fmt.Sprintf("duration: %dms", f.Duration),
+			fmt.Sprintf("blend: %v, dispose to background: %v", f.Blend, f.DisposeToBackground),
+		}
+	case paneFindings:
+		if i >= len(v.inspection.Findings) {
+			return nil
+		}
+		f := v.inspection.Findings[i]
+		return []string{
+			f.Message,
+			fmt.Sprintf("range: %d..%d (%d bytes)", f.Offset, f.Offset+f.Length, f.Length),
+			"bytes: " + v.hexPreview(f.Offset, f.Length),
+		}
+	}
+	return nil
+}
+
+// hexPreview renders up to the first 16 bytes of a range.
+func (v *inspectView) hexPreview(offset, length uint64) string {
+	end := min(offset+length, offset+16, uint64(len(v.data)))
+	if offset >= end {
+		return "(empty)"
+	}
+	preview := fmt.Sprintf("% x", v.data[offset:end])
+	if end < offset+length {
+		preview += " ..."
+	}
+	return preview
+}
+
+// render draws the current pane, windowed around the cursor, and the
+// detail of the selected item.
+func (v *inspectView) render(w io.Writer, clear bool) {
+	if clear {
+		if v.preview == previewKitty {
+			// Delete the previous preview along with the text.
+			fmt.Fprint(w, "\x1b_Ga=d,q=2\x1b\\")
+		}
+		fmt.Fprint(w, "\x1b[H\x1b[2J")
+	}
+	v.printHeader(w)
+
+	rows := v.rows(v.pane)
+	fmt.Fprintf(w, "\n%s (%d):\n", paneNames[v.pane], len(rows))
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	cursor := v.cursor[v.pane]
+	start := max(0, min(cursor-inspectWindow/2, len(rows)-inspectWindow))
+	for i := start; i < len(rows) && i < start+inspectWindow; i++ {
+		marker := " "
+		if i == cursor {
+			marker = ">"
+		}
+		fmt.Fprintf(w, "%s %3d  %s\n", marker, i+1, rows[i])
+	}
+
+	if detail := v.detail(); len(detail) > 0 {
+		fmt.Fprintln(w)
+		for _, line := range detail {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	if v.pane == paneFrames && v.preview != previewNone && cursor < len(v.frames) {
+		fmt.Fprintln(w)
+		if img, err := v.framePreview(cursor); err != nil {
+			fmt.Fprintf(w, "  (no preview: %v)\n", err)
+		} else if err := writePreview(w, v.preview, img); err != nil {
+			fmt.Fprintf(w, "  (no preview: %v)\n", err)
+		}
+	}
+	if v.keys {
+		fmt.Fprintf(w, "\nc chunks  f frames  e findings  tab/←/→ pane  ↑/↓ j/k move  g/G first/last  <n>⏎ select  q quit > %s", v.pending)
+		return
+	}
+	fmt.Fprint(w, "\nc chunks  f frames  e findings  j/k move  <n> select  q quit > ")
+}
+
+// framePreview returns frame i composited onto the canvas, as a viewer
+// would show it, decoding every frame on first use.
+func (v *inspectView) framePreview(i int) (*image.RGBA, error) {
+	if v.decoded == nil && v.decodeErr == nil {
+		decoded, err := webpvalidator.OpenBytes(v.data)
+		if err == nil {
+			v.decoded, err = decoded.Frames()
+		}
+		v.decodeErr = err
+	}
+	if v.decodeErr != nil {
+		return nil, v.decodeErr
+	}
+	if i >= len(v.decoded) {
+		return nil, fmt.Errorf("frame #%d did not decode", i+1)
+	}
+	return v.decoded[i].Image, nil
+}
+
+// interact reads one command per line from r until q or EOF.
+func (v *inspectView) interact(r io.Reader, w io.Writer, clear bool) {
+	scanner := bufio.NewScanner(r)
+	v.render(w, clear)
+	for scanner.Scan() {
+		if !v.handle(strings.TrimSpace(scanner.Text())) {
+			fmt.Fprintln(w)
+			return
+		}
+		v.render(w, clear)
+	}
+	fmt.Fprintln(w)
+}
+
+// interactRaw puts the terminal into raw mode and reads single key
+// presses from it until q. The terminal is restored on return, and on
+// SIGINT or SIGTERM, which then exit.
+func (v *inspectView) interactRaw(in *os.File, w io.Writer) error {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	restore := sync.OnceFunc(func() { term.Restore(fd, state) })
+	defer restore()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		if _, ok := <-signals; ok {
+			restore()
+			fmt.Fprintln(w)
+			os.Exit(exitError)
+		}
+	}()
+
+	// Raw mode also turns off output processing, so newlines need their
+	// carriage return.
+	v.interactKeys(in, crlfWriter{w})
+	return nil
+}
+
+// interactKeys reads key presses from r until q, Escape, Ctrl-C or EOF,
+// redrawing the screen after each.
+func (v *inspectView) interactKeys(r io.Reader, w io.Writer) {
+	v.keys = true
+	keys := bufio.NewReader(r)
+	v.render(w, true)
+	for {
+		cmd, err := readKey(keys)
+		if err != nil {
+			break
+		}
+		switch {
+		case len(cmd) == 1 && cmd[0] >= '0' && cmd[0] <= '9':
+			v.pending += cmd
+		case cmd == "backspace":
+			v.pending = v.pending[:max(len(v.pending)-1, 0)]
+		case cmd == "enter" && v.pending != "":
+			v.handle(v.pending)
+			v.pending = ""
+		default:
+			v.pending = ""
+			if cmd == "enter" {
+				cmd = "j"
+			}
+			if !v.handle(cmd) {
+				fmt.Fprintln(w)
+				return
+			}
+		}
+		v.render(w, true)
+	}
+	fmt.Fprintln(w)
+}
+
+// readKey reads one key press and returns it as a handle command: arrow
+// keys are mapped to h/j/k/l, and Escape and Ctrl-C quit. Enter and
+// Backspace are returned as "enter" and "backspace".
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case '\r', '\n':
+		return "enter", nil
+	case 0x7f, '\b':
+		return "backspace", nil
+	case '\t':
+		return "l", nil
+	case 0x03, 0x04:
+		return "q", nil
+	case 0x1b:
+		// Terminals send an escape sequence in one write, so a lone
+		// Escape has nothing buffered after it.
+		if r.Buffered() == 0 {
+			return "q", nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return "", nil
+		}
+		// Skip parameters such as the "1;5" of a modified arrow key.
+		final, err := r.ReadByte()
+		for err == nil && (final >= '0' && final <= '9' || final == ';') {
+			final, err = r.ReadByte()
+		}
+		switch final {
+		case 'A':
+			return "k", nil
+		case 'B':
+			return "j", nil
+		case 'C':
+			return "l", nil
+		case 'D':
+			return "h", nil
+		case 'H':
+			return "g", nil
+		case 'F':
+			return "G", nil
+		}
+		return "", err
+	}
+	return string(b), nil
+}
+
+// crlfWriter turns "\n" into "\r\n", for a terminal in raw mode.
+type crlfWriter struct{ w io.Writer }
+
+func (c crlfWriter) Write(p []byte) (int, error) {
+	if _, err := c.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// handle applies one command and reports whether to keep going.
+func (v *inspectView) handle(cmd string) bool {
+	count := len(v.rows(v.pane))
+	switch cmd {
+	case "q", "quit":
+		return false
+	case "c":
+		v.pane = paneChunks
+	case "f":
+		v.pane = paneFrames
+	case "e":
+		v.pane = paneFindings
+	case "l":
+		v.pane = (v.pane + 1) % inspectPane(len(paneNames))
+	case "h":
+		v.pane = (v.pane + inspectPane(len(paneNames)) - 1) % inspectPane(len(paneNames))
+	case "j", "":
+		v.cursor[v.pane] = min(v.cursor[v.pane]+1, max(count-1, 0))
+	case "k":
+		v.cursor[v.pane] = max(v.cursor[v.pane]-1, 0)
+	case "g":
+		v.cursor[v.pane] = 0
+	case "G":
+		v.cursor[v.pane] = max(count-1, 0)
+	default:
+		if n, err := strconv.Atoi(cmd); err == nil && n >= 1 && n <= count {
+			v.cursor[v.pane] = n - 1
+		}
+	}
+	return true
+}
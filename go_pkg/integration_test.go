@@ -0,0 +1,246 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"webpValidatorTest/assertwebp"
+	"webpValidatorTest/report"
+	"webpValidatorTest/webpvalidator"
+)
+
+// Run with:
+//
+//	go test -tags integration -run Integration
+//	go test -tags integration -run Integration -update   # rewrite goldens
+var update = flag.Bool("update", false, "rewrite testdata/golden files")
+
+// integrationBinary is the CLI built once by TestMain.
+var integrationBinary string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	dir, err := os.MkdirTemp("", "webp-validator-integration")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	integrationBinary = filepath.Join(dir, "webp-validator")
+	out, err := exec.Command("go", "build", "-o", integrationBinary, ".").CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "building the CLI failed: %v\n%s", err, out)
+		os.Exit(exitError)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// cliRun is one invocation of the built binary.
+type cliRun struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+// runBinary runs the CLI in dir and returns its exit code and output.
+func runBinary(t *testing.T, dir, stdin string, args ...string) cliRun {
+	t.Helper()
+
+	cmd := exec.Command(integrationBinary, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("running %v: %v", args, err)
+	}
+	return cliRun{cmd.ProcessState.ExitCode(), stdout.String(), stderr.String()}
+}
+
+// decoderError matches the decoder's own error description, which
+// differs between image-webp releases and is not part of the contract.
+var decoderError = regexp.MustCompile(`(webp format validation failed: )(?:[^"\\\n]|\\.)*`)
+
+// assertGolden compares got with testdata/golden/name, or rewrites the
+// file with -update.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	got = decoderError.ReplaceAllString(got, "${1}<decoder error>")
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file; run with -update")
+	assert.Equal(t, string(want), got, "output differs from %s; run with -update if intended", path)
+}
+
+// fetchFixtures creates the fixture set with the binary under test and
+// returns its directory.
+func fetchFixtures(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	run := runBinary(t, dir, "", "fixtures", "-dir", "fixtures", "fetch")
+	require.Equal(t, exitOK, run.code, run.stderr)
+	return filepath.Join(dir, "fixtures")
+}
+
+func TestIntegrationUsage(t *testing.T) {
+	run := runBinary(t, t.TempDir(), "", "help")
+	assert.Equal(t, exitError, run.code)
+	assertGolden(t, "usage.golden", run.stderr)
+
+	run = runBinary(t, t.TempDir(), "", "nosuchcommand")
+	assert.Equal(t, exitError, run.code)
+	assert.Contains(t, run.stderr, "unknown command: nosuchcommand")
+}
+
+func TestIntegrationFixturesList(t *testing.T) {
+	run := runBinary(t, t.TempDir(), "", "fixtures", "list")
+	assert.Equal(t, exitOK, run.code)
+	assertGolden(t, "fixtures-list.golden", run.stdout)
+}
+
+func TestIntegrationConformance(t *testing.T) {
+	run := runBinary(t, t.TempDir(), "", "conformance")
+	assert.Equal(t, exitOK, run.code, run.stderr)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	assertGolden(t, "conformance.golden", strings.Replace(run.stdout, platform, "<platform>", 1))
+
+	run = runBinary(t, t.TempDir(), "", "conformance", "-format", "jsonl")
+	assert.Equal(t, exitOK, run.code, run.stderr)
+	assert.Empty(t, run.stdout, "a certified library has no findings")
+}
+
+func TestIntegrationVerdict(t *testing.T) {
+	dir := fetchFixtures(t)
+
+	for _, tc := range []struct {
+		fixture string
+		code    int
+	}{
+		{webpvalidator.FixtureStatic, exitOK},
+		{webpvalidator.FixtureAnimated, exitOK},
+		{webpvalidator.FixtureMetadata, exitOK},
+		{webpvalidator.FixtureTrailingData, exitOK},
+		{webpvalidator.FixtureTruncated, exitFindings},
+		{webpvalidator.FixtureChunkOverflow, exitFindings},
+	} {
+		run := runBinary(t, dir, "", "verdict", tc.fixture)
+		assert.Equal(t, tc.code, run.code, "%s: %s", tc.fixture, run.stderr)
+		assertGolden(t, "verdict-"+strings.TrimSuffix(tc.fixture, ".webp")+".golden", run.stdout)
+	}
+
+	run := runBinary(t, dir, "", "verdict", "-format", "csv", webpvalidator.FixtureChunkOverflow)
+	assert.Equal(t, exitFindings, run.code, run.stderr)
+	assertGolden(t, "verdict-chunk-overflow.csv.golden", run.stdout)
+
+	run = runBinary(t, dir, "", "verdict", "missing.webp")
+	assert.Equal(t, exitError, run.code)
+	assert.Contains(t, run.stderr, "failed to read file")
+}
+
+func TestIntegrationDump(t *testing.T) {
+	dir := fetchFixtures(t)
+
+	run := runBinary(t, dir, "", "dump", "-hex", webpvalidator.FixtureMetadata)
+	assert.Equal(t, exitOK, run.code, run.stderr)
+	assertGolden(t, "dump-metadata.golden", run.stdout)
+}
+
+func TestIntegrationLintRepoPolicies(t *testing.T) {
+	fixtures := fetchFixtures(t)
+	root := t.TempDir()
+	copyFixture := func(name, dest string) {
+		data, err := os.ReadFile(filepath.Join(fixtures, name))
+		require.NoError(t, err)
+		path := filepath.Join(root, filepath.FromSlash(dest))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	copyFixture(webpvalidator.FixtureStatic, "public/ok.webp")
+	copyFixture(webpvalidator.FixtureNotWebp, "public/logo.webp")
+	copyFixture(webpvalidator.FixtureAnimated, "assets/banners/spinner.webp")
+	copyFixture(webpvalidator.FixtureStatic, "assets/banners/big.webp")
+	copyFixture(webpvalidator.FixtureTruncated, "static/drafts/wip.webp")
+	copyFixture(webpvalidator.FixtureTruncated, "src/outside.webp")
+	writeTree(t, root, map[string]string{
+		"assets/banners/.webp-policy.json": `{"allow_animated": false, "max_bytes": 40}`,
+		"static/drafts/.webp-policy.json":  `{"ignore": ["wip.webp"]}`,
+	})
+
+	run := runBinary(t, root, "", "lintrepo")
+	assert.Equal(t, exitFindings, run.code)
+	assertGolden(t, "lintrepo.golden", run.stdout)
+	assert.Equal(t, "4 finding(s)\n", run.stderr)
+
+	run = runBinary(t, root, "", "lintrepo", "-all", "-format", "json")
+	assert.Equal(t, exitFindings, run.code)
+	assertGolden(t, "lintrepo-all.json.golden", run.stdout)
+
+	// Paths piped in, as a pre-commit hook would.
+	run = runBinary(t, root, "public/ok.webp\npublic/logo.webp\nREADME.md\n", "changed", "-format", "jsonl")
+	assert.Equal(t, exitFindings, run.code)
+	assertGolden(t, "changed.jsonl.golden", run.stdout)
+
+	run = runBinary(t, root, "public/ok.webp\n", "changed")
+	assert.Equal(t, exitOK, run.code, run.stderr)
+	assert.Empty(t, run.stdout)
+}
+
+func TestIntegrationExport(t *testing.T) {
+	dir := fetchFixtures(t)
+
+	run := runBinary(t, dir, "", "export", "-format", "jsonl", webpvalidator.FixtureStatic, webpvalidator.FixtureAnimated, webpvalidator.FixtureMetadata)
+	assert.Equal(t, exitOK, run.code, run.stderr)
+	assertGolden(t, "export.jsonl.golden", run.stdout)
+
+	run = runBinary(t, dir, "", "export", webpvalidator.FixtureStatic, webpvalidator.FixtureAnimated, webpvalidator.FixtureMetadata)
+	assert.Equal(t, exitOK, run.code, run.stderr)
+	assertGolden(t, "export.csv.golden", run.stdout)
+}
+
+func TestIntegrationAssertWebp(t *testing.T) {
+	dir := fetchFixtures(t)
+
+	// The in-process report is the one the verdict command prints.
+	for _, name := range []string{webpvalidator.FixtureAnimated, webpvalidator.FixtureTruncated} {
+		path := filepath.Join(dir, name)
+		run := runBinary(t, dir, "", "verdict", "-compact", path)
+		want, err := report.Parse([]byte(run.stdout))
+		require.NoError(t, err, run.stderr)
+		got, ok := assertwebp.Verdict(t, path)
+		require.True(t, ok)
+		assert.Equal(t, want, got, name)
+	}
+
+	assertwebp.Valid(t, filepath.Join(dir, webpvalidator.FixtureStatic))
+	assertwebp.Dimensions(t, filepath.Join(dir, webpvalidator.FixtureMetadata), 1, 1)
+	assertwebp.Animated(t, filepath.Join(dir, webpvalidator.FixtureAnimated), 3)
+	assertwebp.Invalid(t, filepath.Join(dir, webpvalidator.FixtureTruncated))
+}
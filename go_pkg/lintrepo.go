@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+// assetDirNames are the directory names front-end projects conventionally
+// keep static images in. lintrepo only scans files below one of them
+// unless -all is given.
+var assetDirNames = map[string]bool{
+	"public": true,
+	"assets": true,
+	"static": true,
+}
+
+// skipDirNames are never descended into.
+var skipDirNames = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+func runLintRepo(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lintrepo", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	all := flags.Bool("all", false, "scan every directory, not only public/, assets/ and static/")
+	rate := rateFlag(flags)
+	format := formatFlag(flags, "text")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator lintrepo [-all] [-rate bytes/s] [-format name] [root]")
+		fmt.Fprintf(stderr, "\nvalidates webp assets and enforces %s policy files\n\n", webpvalidator.PolicyFileName)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) > 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	root := "."
+	if len(positional) == 1 {
+		root = positional[0]
+	}
+
+	paths, err := findAssets(root, *all)
+	if err != nil {
+		fmt.Fprintf(stderr, "lintrepo: %v\n", err)
+		return exitError
+	}
+
+	return writeFindings("lintrepo", *format, lintFiles(root, paths, webpvalidator.NewThrottle(*rate)), stdout, stderr)
+}
+
+// findAssets returns the .webp files below root, relative to root.
+// Unless all is set, only files inside an asset directory are returned.
+func findAssets(root string, all bool) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDirNames[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isWebpName(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if all || inAssetDir(rel) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+func isWebpName(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".webp")
+}
+
+// inAssetDir reports whether any directory in rel is an asset directory.
+func inAssetDir(rel string) bool {
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if assetDirNames[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// lintFiles validates each path (relative to root) against the webp format
+// and the policy in effect for its directory. Files are read ahead of
+// validation (see webpvalidator.PrefetchFiles), so a scan is bound by the
+// disk or the decoder, whichever is slower, rather than by both in turn.
+func lintFiles(root string, paths []string, throttle *webpvalidator.Throttle) []pathFinding {
+	policies := webpvalidator.NewPolicyResolver(root)
+	var findings []pathFinding
+
+	// Resolve policies first so ignored files are never read.
+	type lintJob struct {
+		slashed string
+		policy  webpvalidator.AssetPolicy
+	}
+	var jobs []lintJob
+	var reads []string
+	for _, rel := range paths {
+		path := filepath.Join(root, rel)
+		slashed := filepath.ToSlash(rel)
+
+		policy, err := policies.Resolve(filepath.Dir(path))
+		if err != nil {
+			findings = append(findings, errorFinding(slashed, err.Error()))
+			continue
+		}
+		if policy.Ignores(filepath.Base(rel)) {
+			continue
+		}
+		jobs = append(jobs, lintJob{slashed, policy})
+		reads = append(reads, path)
+	}
+
+	files := webpvalidator.PrefetchFiles(reads, throttle)
+	for _, job := range jobs {
+		file := <-files
+		if file.Err != nil {
+			findings = append(findings, errorFinding(job.slashed, file.Err.Error()))
+			continue
+		}
+
+		info := webpvalidator.ValidateWebp(file.Data)
+		if err := file.Snapshot.Verify(); err != nil {
+			findings = append(findings, errorFinding(job.slashed, err.Error()))
+			continue
+		}
+		if !info.IsValid {
+			findings = append(findings, errorFinding(job.slashed, "invalid webp: "+info.Error))
+			continue
+		}
+		for _, violation := range job.policy.Check(info, int64(len(file.Data))) {
+			findings = append(findings, errorFinding(job.slashed, violation))
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+	return findings
+}
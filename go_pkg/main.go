@@ -3,31 +3,29 @@ package main
 import (
 	"fmt"
 	"os"
+
+	"webpValidatorTest/webpvalidator"
 )
 
 func main() {
-	fmt.Println("=== WebP Validator - Go Calling Rust ===")
-
-	testFiles := []struct {
-		path        string
-		description string
-	}{
-		{"../images/dynamic.webp", "dynamic webp"},
-		{"../images/static.webp", "static webp"},
-		{"../images/fake.webp", "fake webp (jpg renamed)"},
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 	}
 
-	for _, test := range testFiles {
-		fmt.Printf("testing: %s\n", test.description)
-		fmt.Printf("  file: %s\n", test.path)
+	runDemo()
+}
+
+// runDemo validates the standard fixtures and prints the results. They
+// are built in memory, so the demo runs from any directory.
+func runDemo() {
+	fmt.Println("=== WebP Validator - Go Calling Rust ===")
 
-		data, err := os.ReadFile(test.path)
-		if err != nil {
-			fmt.Printf("  error reading file: %v\n\n", err)
-			continue
-		}
+	for _, fixture := range webpvalidator.Fixtures() {
+		fmt.Printf("testing: %s\n", fixture.Description)
+		fmt.Printf("  fixture: %s\n", fixture.Name)
 
-		info := ValidateWebp(data)
+		data := fixture.Data()
+		info := webpvalidator.ValidateWebp(data)
 
 		if info.IsValid {
 			fmt.Println("  result: valid webp file")
@@ -40,6 +38,9 @@ func main() {
 		} else {
 			fmt.Println("  result: invalid webp file")
 			fmt.Printf("  error: %s\n", info.Error)
+			if info.Partial {
+				fmt.Printf("  partial metadata: %dx%d, %d frames\n", info.Width, info.Height, info.NumFrames)
+			}
 		}
 		fmt.Println()
 	}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"webpValidatorTest/report"
+)
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// parquetRowGroupRows is how many rows are buffered before they are
+// written out as a row group.
+const parquetRowGroupRows = 1 << 14
+
+// Parquet physical types, encodings and other enum values used below,
+// from parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional     = 1
+	parquetUTF8         = 0
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// parquetFormatter writes tables, and findings as a table of
+// findingColumns, as a Parquet file. It is deliberately minimal: every
+// column is OPTIONAL so findings without a byte range can hold nulls, and
+// each column chunk is a single uncompressed PLAIN data page. Rows are
+// buffered and written one row group at a time.
+type parquetFormatter struct {
+	w         *offsetWriter
+	groupRows int
+
+	columns []report.Column
+	rows    [][]any
+	groups  []parquetRowGroup
+	numRows int64
+}
+
+// parquetRowGroup is where a written row group's column chunks are, for the
+// file footer.
+type parquetRowGroup struct {
+	numRows int64
+	chunks  []parquetColumnChunk
+}
+
+type parquetColumnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// offsetWriter tracks the offset of the next write and keeps the first
+// write error, so a file can be laid out without checking every write.
+type offsetWriter struct {
+	w      io.Writer
+	offset int64
+	err    error
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	w.err = err
+	return n, err
+}
+
+func newParquetFormatter(w io.Writer) *parquetFormatter {
+	return &parquetFormatter{w: &offsetWriter{w: w}, groupRows: parquetRowGroupRows}
+}
+
+func (f *parquetFormatter) Begin(string) error {
+	_, err := io.WriteString(f.w, parquetMagic)
+	return err
+}
+
+func (f *parquetFormatter) Columns(columns []report.Column) error {
+	for _, column := range columns {
+		if _, err := parquetType(column.Type); err != nil {
+			return fmt.Errorf("column %s: %w", column.Name, err)
+		}
+	}
+	f.columns = columns
+	return nil
+}
+
+func (f *parquetFormatter) Row(values []any) error {
+	if len(values) != len(f.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(values), len(f.columns))
+	}
+	for i, v := range values {
+		if !parquetValueMatches(f.columns[i].Type, v) {
+			return fmt.Errorf("column %s: %T is not a %s value", f.columns[i].Name, v, f.columns[i].Type)
+		}
+	}
+	f.rows = append(f.rows, values)
+	if len(f.rows) >= f.groupRows {
+		return f.flush()
+	}
+	return nil
+}
+
+func (f *parquetFormatter) Finding(path string, finding report.Finding) error {
+	if f.columns == nil {
+		if err := f.Columns(findingColumns); err != nil {
+			return err
+		}
+	}
+	return f.Row(findingValues(path, finding))
+}
+
+func (f *parquetFormatter) End() error {
+	if f.columns == nil {
+		if err := f.Columns(findingColumns); err != nil {
+			return err
+		}
+	}
+	if err := f.flush(); err != nil {
+		return err
+	}
+
+	footer := f.footer()
+	f.w.Write(footer)
+	binary.Write(f.w, binary.LittleEndian, uint32(len(footer)))
+	io.WriteString(f.w, parquetMagic)
+	return f.w.err
+}
+
+// flush writes the buffered rows as a row group.
+func (f *parquetFormatter) flush() error {
+	if len(f.rows) == 0 {
+		return f.w.err
+	}
+
+	group := parquetRowGroup{numRows: int64(len(f.rows))}
+	for i := range f.columns {
+		page := parquetPage(f.rows, i)
+
+		var header thriftWriter
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5, func() {
+			header.i32(1, int32(len(f.rows)))
+			header.i32(2, parquetPlain)
+			header.i32(3, parquetRLE)
+			header.i32(4, parquetRLE)
+		})
+		header.stop()
+
+		chunk := parquetColumnChunk{offset: f.w.offset, numValues: int64(len(f.rows))}
+		f.w.Write(header.buf.Bytes())
+		f.w.Write(page)
+		chunk.size = f.w.offset - chunk.offset
+		group.chunks = append(group.chunks, chunk)
+	}
+
+	f.groups = append(f.groups, group)
+	f.numRows += group.numRows
+	f.rows = f.rows[:0]
+	return f.w.err
+}
+
+// footer returns the FileMetaData of everything written so far.
+func (f *parquetFormatter) footer() []byte {
+	var t thriftWriter
+	t.i32(1, 1)
+	t.list(2, thriftStruct, len(f.columns)+1, func(i int) {
+		t.structElem(func() {
+			if i == 0 {
+				t.binary(4, "schema")
+				t.i32(5, int32(len(f.columns)))
+				return
+			}
+			column := f.columns[i-1]
+			physical, _ := parquetType(column.Type)
+			t.i32(1, physical)
+			t.i32(3, parquetOptional)
+			t.binary(4, column.Name)
+			if column.Type == report.ColumnString {
+				t.i32(6, parquetUTF8)
+			}
+		})
+	})
+	t.i64(3, f.numRows)
+	t.list(4, thriftStruct, len(f.groups), func(g int) {
+		group := f.groups[g]
+		t.structElem(func() {
+			var groupSize int64
+			t.list(1, thriftStruct, len(group.chunks), func(c int) {
+				chunk := group.chunks[c]
+				groupSize += chunk.size
+				physical, _ := parquetType(f.columns[c].Type)
+				t.structElem(func() {
+					t.i64(2, chunk.offset)
+					t.structField(3, func() {
+						t.i32(1, physical)
+						t.list(2, thriftI32, 2, func(e int) { t.elemI32([]int32{parquetPlain, parquetRLE}[e]) })
+						t.list(3, thriftBinary, 1, func(int) { t.elemBinary(f.columns[c].Name) })
+						t.i32(4, parquetUncompressed)
+						t.i64(5, chunk.numValues)
+						t.i64(6, chunk.size)
+						t.i64(7, chunk.size)
+						t.i64(9, chunk.offset)
+					})
+				})
+			})
+			t.i64(2, groupSize)
+			t.i64(3, group.numRows)
+		})
+	})
+	t.binary(6, "webp-validator")
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// parquetType returns the physical type column values are stored as.
+func parquetType(t report.ColumnType) (int32, error) {
+	switch t {
+	case report.ColumnString:
+		return parquetByteArray, nil
+	case report.ColumnBool:
+		return parquetBoolean, nil
+	case report.ColumnInt:
+		return parquetInt64, nil
+	case report.ColumnFloat:
+		return parquetDouble, nil
+	}
+	return 0, fmt.Errorf("unsupported column type %q", t)
+}
+
+// parquetValueMatches reports whether v can be stored in a column of type
+// t. Any column may hold nil.
+func parquetValueMatches(t report.ColumnType, v any) bool {
+	switch v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == report.ColumnString
+	case bool:
+		return t == report.ColumnBool
+	case int64:
+		return t == report.ColumnInt
+	case float64:
+		return t == report.ColumnFloat
+	}
+	return false
+}
+
+// parquetPage encodes a column of rows as the body of a v1 data page: the
+// definition levels (1 for a value, 0 for null), then the non-null values.
+func parquetPage(rows [][]any, column int) []byte {
+	var levels bytes.Buffer
+	for start := 0; start < len(rows); {
+		defined := rows[start][column] != nil
+		end := start + 1
+		for end < len(rows) && (rows[end][column] != nil) == defined {
+			end++
+		}
+		// An RLE run: its length shifted left once, then the level in
+		// one byte since the bit width is 1.
+		levels.Write(binary.AppendUvarint(nil, uint64(end-start)<<1))
+		if defined {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		start = end
+	}
+
+	page := binary.LittleEndian.AppendUint32(nil, uint32(levels.Len()))
+	page = append(page, levels.Bytes()...)
+
+	var bits, count int
+	for _, row := range rows {
+		switch v := row[column].(type) {
+		case string:
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(v)))
+			page = append(page, v...)
+		case int64:
+			page = binary.LittleEndian.AppendUint64(page, uint64(v))
+		case float64:
+			page = binary.LittleEndian.AppendUint64(page, math.Float64bits(v))
+		case bool:
+			// Booleans are bit-packed, least significant bit first.
+			if count%8 == 0 {
+				page = append(page, 0)
+				bits = len(page) - 1
+			}
+			if v {
+				page[bits] |= 1 << (count % 8)
+			}
+			count++
+		}
+	}
+	return page
+}
+
+// Thrift compact protocol types, as used in field and list headers.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes a struct in the Thrift compact protocol, which is
+// what Parquet metadata is stored as. Fields must be written in id order.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	lastID int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+// varint writes a zigzag varint.
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.elemBinary(s)
+}
+
+func (t *thriftWriter) structField(id int16, fields func()) {
+	t.field(id, thriftStruct)
+	t.structElem(fields)
+}
+
+// list writes a list field of n elements, each written by elem with one
+// of the elem* methods.
+func (t *thriftWriter) list(id int16, elemType byte, n int, elem func(i int)) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+	}
+	for i := range n {
+		elem(i)
+	}
+}
+
+func (t *thriftWriter) elemI32(v int32) { t.varint(int64(v)) }
+
+func (t *thriftWriter) elemBinary(s string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
+
+// structElem writes a nested struct, whose field ids start over.
+func (t *thriftWriter) structElem(fields func()) {
+	saved := t.lastID
+	t.lastID = 0
+	fields()
+	t.stop()
+	t.lastID = saved
+}
+
+// stop ends the current struct.
+func (t *thriftWriter) stop() { t.buf.WriteByte(0) }
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"webpValidatorTest/report"
+	"webpValidatorTest/webpvalidator"
+)
+
+// thriftReader decodes Thrift compact protocol structs into maps from
+// field id to value, so the tests can check the exact layout
+// parquetFormatter wrote.
+type thriftReader struct {
+	t    *testing.T
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() byte {
+	r.t.Helper()
+	require.Less(r.t, r.pos, len(r.data), "thrift data truncated")
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	r.t.Helper()
+	v, n := binary.Uvarint(r.data[r.pos:])
+	require.Positive(r.t, n, "bad varint at %d", r.pos)
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) any {
+	r.t.Helper()
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, thriftI32, thriftI64:
+		return r.zigzag()
+	case 7:
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v
+	case thriftBinary:
+		n := int(r.uvarint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		header := r.byte()
+		n := int(header >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.structValue()
+	}
+	r.t.Fatalf("unsupported thrift type %d at %d", typ, r.pos)
+	return nil
+}
+
+func (r *thriftReader) structValue() map[int16]any {
+	fields := map[int16]any{}
+	var id int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(header & 0x0f)
+	}
+}
+
+// readParquet decodes a file written by parquetFormatter into its column
+// names and rows, with nil for nulls.
+func readParquet(t *testing.T, data []byte) ([]string, [][]any) {
+	t.Helper()
+	require.Greater(t, len(data), 12)
+	require.Equal(t, parquetMagic, string(data[:4]))
+	require.Equal(t, parquetMagic, string(data[len(data)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{t: t, data: data[:len(data)-8], pos: len(data) - 8 - footerLen}
+	meta := footer.structValue()
+
+	schema := meta[2].([]any)
+	require.Equal(t, int64(len(schema)-1), schema[0].(map[int16]any)[5], "root num_children")
+	var names []string
+	var types []int64
+	for _, element := range schema[1:] {
+		element := element.(map[int16]any)
+		assert.Equal(t, int64(parquetOptional), element[3])
+		names = append(names, element[4].(string))
+		types = append(types, element[1].(int64))
+	}
+
+	var rows [][]any
+	for _, group := range meta[4].([]any) {
+		group := group.(map[int16]any)
+		numRows := int(group[3].(int64))
+		groupRows := make([][]any, numRows)
+		for i := range groupRows {
+			groupRows[i] = make([]any, len(names))
+		}
+
+		for c, chunk := range group[1].([]any) {
+			columnMeta := chunk.(map[int16]any)[3].(map[int16]any)
+			assert.Equal(t, types[c], columnMeta[1])
+			assert.Equal(t, []any{names[c]}, columnMeta[3])
+			assert.Equal(t, int64(numRows), columnMeta[5])
+
+			page := &thriftReader{t: t, data: data, pos: int(columnMeta[9].(int64))}
+			header := page.structValue()
+			body := data[page.pos : page.pos+int(header[3].(int64))]
+			assert.Equal(t, int64(page.pos+len(body))-columnMeta[9].(int64), columnMeta[7], "chunk size")
+
+			levelsLen := int(binary.LittleEndian.Uint32(body))
+			levels := &thriftReader{t: t, data: body[4 : 4+levelsLen]}
+			var defined []bool
+			for levels.pos < len(levels.data) {
+				run := levels.uvarint()
+				require.Zero(t, run&1, "only RLE runs are written")
+				level := levels.byte()
+				for range run >> 1 {
+					defined = append(defined, level == 1)
+				}
+			}
+			require.Len(t, defined, numRows)
+
+			values := body[4+levelsLen:]
+			bit := 0
+			for r := range groupRows {
+				if !defined[r] {
+					continue
+				}
+				switch types[c] {
+				case parquetByteArray:
+					n := int(binary.LittleEndian.Uint32(values))
+					groupRows[r][c] = string(values[4 : 4+n])
+					values = values[4+n:]
+				case parquetInt64:
+					groupRows[r][c] = int64(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+				case parquetDouble:
+					groupRows[r][c] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+				case parquetBoolean:
+					groupRows[r][c] = values[bit/8]&(1<<(bit%8)) != 0
+					bit++
+				}
+			}
+		}
+		rows = append(rows, groupRows...)
+	}
+	assert.Equal(t, int64(len(rows)), meta[3], "num_rows")
+	return names, rows
+}
+
+func TestParquetFormatterRoundTrip(t *testing.T) {
+	columns := []report.Column{
+		{Name: "name", Type: report.ColumnString},
+		{Name: "ok", Type: report.ColumnBool},
+		{Name: "count", Type: report.ColumnInt},
+		{Name: "ratio", Type: report.ColumnFloat},
+	}
+	var rows [][]any
+	for i := range 11 {
+		row := []any{string(rune('a' + i)), i%3 == 0, int64(i * 1000), float64(i) / 4}
+		if i%4 == 1 {
+			row[2] = nil
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	f := newParquetFormatter(&buf)
+	f.groupRows = 4
+	require.NoError(t, f.Begin("export"))
+	require.NoError(t, f.Columns(columns))
+	for _, row := range rows {
+		require.NoError(t, f.Row(row))
+	}
+	require.NoError(t, f.End())
+
+	names, got := readParquet(t, buf.Bytes())
+	assert.Equal(t, []string{"name", "ok", "count", "ratio"}, names)
+	assert.Equal(t, rows, got)
+
+	f = newParquetFormatter(&bytes.Buffer{})
+	require.NoError(t, f.Begin("export"))
+	require.NoError(t, f.Columns(columns))
+	assert.ErrorContains(t, f.Row([]any{"a", true, 1, 0.5}), "column count: int is not a int value")
+}
+
+func TestParquetFormatterFindings(t *testing.T) {
+	offset, length := uint64(12), uint64(30)
+	var stdout, stderr bytes.Buffer
+	code := writeFindings("verdict", "parquet", []pathFinding{
+		{"a.webp", report.Finding{Severity: report.SeverityError, Message: "overflow", Offset: &offset, Length: &length}},
+		errorFinding("b.webp", "unreadable"),
+	}, &stdout, &stderr)
+	assert.Equal(t, exitFindings, code, stderr.String())
+
+	names, rows := readParquet(t, stdout.Bytes())
+	assert.Equal(t, []string{"path", "severity", "message", "offset", "length"}, names)
+	assert.Equal(t, [][]any{
+		{"a.webp", "error", "overflow", int64(12), int64(30)},
+		{"b.webp", "error", "unreadable", nil, nil},
+	}, rows)
+
+	stdout.Reset()
+	assert.Equal(t, exitOK, writeFindings("lintrepo", "parquet", nil, &stdout, &stderr))
+	names, rows = readParquet(t, stdout.Bytes())
+	assert.Len(t, names, len(findingColumns))
+	assert.Empty(t, rows)
+}
+
+func TestExportParquet(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureAnimated)
+	code, stdout, stderr := runCLIForTest("export", "-format", "parquet", path)
+	assert.Equal(t, exitOK, code, stderr)
+
+	names, rows := readParquet(t, []byte(stdout))
+	require.Len(t, names, len(featureColumns))
+	for i, column := range featureColumns {
+		assert.Equal(t, column.Name, names[i])
+	}
+	require.Len(t, rows, 1)
+	assert.Equal(t, extractFeatures(path, fixtureData(t, webpvalidator.FixtureAnimated)).values(), rows[0])
+}
+
+// readParquetReference decodes data with parquet-go, an independent
+// reader, into the same shape as readParquet.
+func readParquetReference(t *testing.T, data []byte) ([]string, [][]any) {
+	t.Helper()
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	var names []string
+	for _, field := range file.Schema().Fields() {
+		assert.True(t, field.Optional(), "column %s", field.Name())
+		names = append(names, field.Name())
+	}
+
+	rows := [][]any{}
+	for _, group := range file.RowGroups() {
+		reader := group.Rows()
+		buf := make([]parquet.Row, 8)
+		for {
+			n, err := reader.ReadRows(buf)
+			for _, row := range buf[:n] {
+				values := make([]any, len(names))
+				for _, v := range row {
+					switch {
+					case v.IsNull():
+					case v.Kind() == parquet.Boolean:
+						values[v.Column()] = v.Boolean()
+					case v.Kind() == parquet.Int64:
+						values[v.Column()] = v.Int64()
+					case v.Kind() == parquet.Double:
+						values[v.Column()] = v.Double()
+					case v.Kind() == parquet.ByteArray:
+						values[v.Column()] = string(v.ByteArray())
+					default:
+						t.Fatalf("unexpected %s value in column %d", v.Kind(), v.Column())
+					}
+				}
+				rows = append(rows, values)
+			}
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+		}
+		require.NoError(t, reader.Close())
+	}
+	assert.Equal(t, int64(len(rows)), file.NumRows())
+	return names, rows
+}
+
+func TestParquetReferenceReader(t *testing.T) {
+	columns := []report.Column{
+		{Name: "name", Type: report.ColumnString},
+		{Name: "ok", Type: report.ColumnBool},
+		{Name: "count", Type: report.ColumnInt},
+		{Name: "ratio", Type: report.ColumnFloat},
+	}
+	var rows [][]any
+	for i := range 11 {
+		row := []any{string(rune('a' + i)), i%3 == 0, int64(i * 1000), float64(i) / 4}
+		if i%4 == 1 {
+			row[i%len(row)] = nil
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	f := newParquetFormatter(&buf)
+	f.groupRows = 4
+	require.NoError(t, f.Begin("export"))
+	require.NoError(t, f.Columns(columns))
+	for _, row := range rows {
+		require.NoError(t, f.Row(row))
+	}
+	require.NoError(t, f.End())
+
+	names, got := readParquetReference(t, buf.Bytes())
+	assert.Equal(t, []string{"name", "ok", "count", "ratio"}, names)
+	assert.Equal(t, rows, got)
+
+	paths := []string{fixturePath(t, webpvalidator.FixtureAnimated), fixturePath(t, webpvalidator.FixtureNotWebp)}
+	code, stdout, stderr := runCLIForTest(append([]string{"export", "-format", "parquet"}, paths...)...)
+	assert.Equal(t, exitOK, code, stderr)
+	names, got = readParquetReference(t, []byte(stdout))
+	require.Len(t, names, len(featureColumns))
+	for i, column := range featureColumns {
+		assert.Equal(t, column.Name, names[i])
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, extractFeatures(paths[0], fixtureData(t, webpvalidator.FixtureAnimated)).values(), got[0])
+	assert.Equal(t, extractFeatures(paths[1], fixtureData(t, webpvalidator.FixtureNotWebp)).values(), got[1])
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// previewMode is how the interactive inspector draws frame previews.
+type previewMode string
+
+const (
+	previewAuto  previewMode = "auto"
+	previewKitty previewMode = "kitty"
+	previewSixel previewMode = "sixel"
+	previewText  previewMode = "text"
+	previewNone  previewMode = "none"
+)
+
+// Preview sizes: graphics protocols get pixels, the text fallback gets
+// one column per pixel and two pixels per line.
+const (
+	previewPixels     = 256
+	previewTextWidth  = 48
+	previewTextHeight = 32
+)
+
+// kittyChunkSize is the most base64 payload a kitty graphics escape may
+// carry; larger images are sent in several.
+const kittyChunkSize = 4096
+
+func parsePreviewMode(s string) (previewMode, error) {
+	switch mode := previewMode(s); mode {
+	case previewAuto, previewKitty, previewSixel, previewText, previewNone:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid -preview %q: want auto, kitty, sixel, text or none", s)
+}
+
+// resolvePreview picks the protocol for auto from the environment. Output
+// that is not a terminal gets no previews, so pipes and logs stay plain.
+func resolvePreview(mode previewMode, terminal bool, getenv func(string) string) previewMode {
+	if mode != previewAuto {
+		return mode
+	}
+	if !terminal {
+		return previewNone
+	}
+
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case term == "dumb":
+		return previewNone
+	case term == "xterm-kitty" || getenv("KITTY_WINDOW_ID") != "" || program == "WezTerm" || program == "ghostty":
+		return previewKitty
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel"):
+		return previewSixel
+	}
+	return previewText
+}
+
+// writePreview draws img with the given protocol, starting on a new line
+// and leaving the cursor on the line after it.
+func writePreview(w io.Writer, mode previewMode, img *image.RGBA) error {
+	var buf bytes.Buffer
+	switch mode {
+	case previewKitty:
+		if err := kittyImage(&buf, fitImage(img, previewPixels, previewPixels)); err != nil {
+			return err
+		}
+	case previewSixel:
+		sixelImage(&buf, flatten(fitImage(img, previewPixels, previewPixels)))
+	case previewText:
+		halfBlockImage(&buf, flatten(fitImage(img, previewTextWidth, previewTextHeight)))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// fitImage scales img, up or down, to the largest size that fits in
+// maxW x maxH with the same aspect ratio. Nearest-neighbour sampling keeps
+// the pixels of tiny or pixel-art frames sharp.
+func fitImage(img *image.RGBA, maxW, maxH int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+	tw, th := maxW, h*maxW/w
+	if th > maxH {
+		tw, th = w*maxH/h, maxH
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	out := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := range th {
+		src := img.Pix[(y*h/th)*img.Stride:]
+		dst := out.Pix[y*out.Stride:]
+		for x := range tw {
+			copy(dst[x*4:x*4+4], src[(x*w/tw)*4:])
+		}
+	}
+	return out
+}
+
+// flatten composites img over a checkerboard, for protocols without
+// transparency. Decoded frames hold straight, not premultiplied, alpha.
+func flatten(img *image.RGBA) *image.RGBA {
+	out := image.NewRGBA(img.Rect)
+	for y := range img.Rect.Dy() {
+		for x := range img.Rect.Dx() {
+			i := y*img.Stride + x*4
+			bg := uint32(0xcc)
+			if (x/8+y/8)%2 == 1 {
+				bg = 0x99
+			}
+			a := uint32(img.Pix[i+3])
+			for c := range 3 {
+				out.Pix[i+c] = uint8((uint32(img.Pix[i+c])*a + bg*(255-a)) / 255)
+			}
+			out.Pix[i+3] = 0xff
+		}
+	}
+	return out
+}
+
+// kittyImage writes img with the kitty graphics protocol as a PNG, with
+// responses suppressed (q=2) so none end up on the inspector's stdin.
+func kittyImage(buf *bytes.Buffer, img *image.RGBA) error {
+	// Decoded frames hold straight alpha, which is what NRGBA means.
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, &image.NRGBA{Pix: img.Pix, Stride: img.Stride, Rect: img.Rect}); err != nil {
+		return err
+	}
+	payload := base64.StdEncoding.EncodeToString(encoded.Bytes())
+
+	buf.WriteString("  ")
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(len(payload), kittyChunkSize)]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(buf, "\x1b_Ga=T,f=100,q=2,m=%d;%s\x1b\\", more, chunk)
+		} else {
+			fmt.Fprintf(buf, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+// sixelImage writes an opaque img as sixels, quantized to a 6x6x6 color
+// cube.
+func sixelImage(buf *bytes.Buffer, img *image.RGBA) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	index := make([]uint8, w*h)
+	var used [216]bool
+	for y := range h {
+		for x := range w {
+			p := img.Pix[y*img.Stride+x*4:]
+			c := uint8(int(p[0])*6/256*36 + int(p[1])*6/256*6 + int(p[2])*6/256)
+			index[y*w+x] = c
+			used[c] = true
+		}
+	}
+
+	fmt.Fprintf(buf, "\x1bPq\"1;1;%d;%d", w, h)
+	for c, ok := range used {
+		if ok {
+			fmt.Fprintf(buf, "#%d;2;%d;%d;%d", c, c/36*20, c/6%6*20, c%6*20)
+		}
+	}
+
+	row := make([]byte, w)
+	for band := 0; band < h; band += 6 {
+		if band > 0 {
+			buf.WriteByte('-')
+		}
+		first := true
+		for c, ok := range used {
+			if !ok {
+				continue
+			}
+			// Each sixel is one column of six pixels of the band, bit 0
+			// at the top; a color is drawn where its bits are set.
+			drawn := false
+			for x := range w {
+				bits := 0
+				for r := range min(6, h-band) {
+					if int(index[(band+r)*w+x]) == c {
+						bits |= 1 << r
+					}
+				}
+				row[x] = byte(63 + bits)
+				drawn = drawn || bits != 0
+			}
+			if !drawn {
+				continue
+			}
+			if !first {
+				buf.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(buf, "#%d", c)
+			sixelRun(buf, row)
+		}
+	}
+	buf.WriteString("\x1b\\\n")
+}
+
+// sixelRun writes row with runs of a repeated sixel compressed to !n.
+func sixelRun(buf *bytes.Buffer, row []byte) {
+	for i := 0; i < len(row); {
+		j := i + 1
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(buf, "!%d%c", n, row[i])
+		} else {
+			buf.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// halfBlockImage draws an opaque img with 24-bit colored upper half
+// blocks, two pixels per character cell, for terminals without a graphics
+// protocol.
+func halfBlockImage(buf *bytes.Buffer, img *image.RGBA) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	for y := 0; y < h; y += 2 {
+		buf.WriteString("  ")
+		for x := range w {
+			top := img.Pix[y*img.Stride+x*4:]
+			fmt.Fprintf(buf, "\x1b[38;2;%d;%d;%dm", top[0], top[1], top[2])
+			if y+1 < h {
+				bottom := img.Pix[(y+1)*img.Stride+x*4:]
+				fmt.Fprintf(buf, "\x1b[48;2;%d;%d;%dm", bottom[0], bottom[1], bottom[2])
+			} else {
+				buf.WriteString("\x1b[49m")
+			}
+			buf.WriteString("▀")
+		}
+		buf.WriteString("\x1b[0m\n")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"webpValidatorTest/webpvalidator"
+)
+
+// testImage returns a w x h image with straight-alpha pixels from fill.
+func testImage(w, h int, fill func(x, y int) [4]uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			p := fill(x, y)
+			copy(img.Pix[y*img.Stride+x*4:], p[:])
+		}
+	}
+	return img
+}
+
+func TestResolvePreview(t *testing.T) {
+	for _, tc := range []struct {
+		env      map[string]string
+		terminal bool
+		want     previewMode
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, false, previewNone},
+		{map[string]string{"TERM": "xterm-kitty"}, true, previewKitty},
+		{map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, true, previewKitty},
+		{map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, true, previewKitty},
+		{map[string]string{"TERM": "foot"}, true, previewSixel},
+		{map[string]string{"TERM": "xterm-sixel"}, true, previewSixel},
+		{map[string]string{"TERM": "xterm-256color"}, true, previewText},
+		{map[string]string{"TERM": "dumb"}, true, previewNone},
+	} {
+		getenv := func(name string) string { return tc.env[name] }
+		assert.Equal(t, tc.want, resolvePreview(previewAuto, tc.terminal, getenv), "%v terminal=%v", tc.env, tc.terminal)
+	}
+	assert.Equal(t, previewSixel, resolvePreview(previewSixel, false, func(string) string { return "" }), "explicit modes are kept")
+
+	_, err := parsePreviewMode("ascii")
+	assert.ErrorContains(t, err, `invalid -preview "ascii"`)
+}
+
+func TestFitImage(t *testing.T) {
+	red := [4]uint8{255, 0, 0, 255}
+	blue := [4]uint8{0, 0, 255, 255}
+	img := testImage(2, 1, func(x, _ int) [4]uint8 { return [2][4]uint8{red, blue}[x] })
+
+	fitted := fitImage(img, 8, 8)
+	assert.Equal(t, image.Rect(0, 0, 8, 4), fitted.Rect, "small images are scaled up, keeping the aspect ratio")
+	assert.Equal(t, red[:], fitted.Pix[fitted.PixOffset(3, 3):][:4])
+	assert.Equal(t, blue[:], fitted.Pix[fitted.PixOffset(4, 0):][:4])
+
+	tall := fitImage(testImage(10, 100, func(int, int) [4]uint8 { return red }), 48, 32)
+	assert.Equal(t, image.Rect(0, 0, 3, 32), tall.Rect)
+}
+
+func TestFlatten(t *testing.T) {
+	img := testImage(16, 1, func(int, int) [4]uint8 { return [4]uint8{255, 255, 255, 0} })
+	img.Pix[3] = 255
+	flat := flatten(img)
+	assert.Equal(t, []uint8{255, 255, 255, 255}, flat.Pix[0:4], "opaque pixels are kept")
+	assert.Equal(t, []uint8{0xcc, 0xcc, 0xcc, 255}, flat.Pix[4:8], "transparent pixels show the checkerboard")
+	assert.Equal(t, []uint8{0x99, 0x99, 0x99, 255}, flat.Pix[8*4:8*4+4])
+}
+
+func TestKittyPreview(t *testing.T) {
+	// Noise does not compress, so the PNG needs several escapes.
+	seed := uint32(1)
+	img := testImage(64, 64, func(int, int) [4]uint8 {
+		seed = seed*1664525 + 1013904223
+		return [4]uint8{uint8(seed >> 24), uint8(seed >> 16), uint8(seed >> 8), 128}
+	})
+
+	var out bytes.Buffer
+	require.NoError(t, writePreview(&out, previewKitty, img))
+	escapes := regexp.MustCompile("\x1b_G([^;]*);([^\x1b]*)\x1b\\\\").FindAllStringSubmatch(out.String(), -1)
+	require.Greater(t, len(escapes), 1)
+	assert.Equal(t, "a=T,f=100,q=2,m=1", escapes[0][1])
+
+	var payload strings.Builder
+	for i, escape := range escapes {
+		assert.LessOrEqual(t, len(escape[2]), kittyChunkSize)
+		if i > 0 {
+			want := "m=1"
+			if i == len(escapes)-1 {
+				want = "m=0"
+			}
+			assert.Equal(t, want, escape[1])
+		}
+		payload.WriteString(escape[2])
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload.String())
+	require.NoError(t, err)
+	decoded, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, previewPixels, previewPixels), decoded.Bounds())
+	p := img.Pix[:4]
+	assert.Equal(t, color.NRGBA{p[0], p[1], p[2], p[3]}, color.NRGBAModel.Convert(decoded.At(0, 0)), "alpha is straight")
+}
+
+func TestSixelPreview(t *testing.T) {
+	img := testImage(1, 1, func(int, int) [4]uint8 { return [4]uint8{255, 0, 0, 255} })
+
+	var out bytes.Buffer
+	require.NoError(t, writePreview(&out, previewSixel, img))
+	sixel := out.String()
+	want := "\x1bPq\"1;1;256;256#180;2;100;0;0"
+	require.True(t, strings.HasPrefix(sixel, want), "%q", sixel[:min(len(sixel), 64)])
+	assert.True(t, strings.HasSuffix(sixel, "\x1b\\\n"))
+	// 256 rows are 43 bands of six, each a single run of full sixels
+	// except the last, which has four rows.
+	assert.Equal(t, 42, strings.Count(sixel, "#180!256~-"))
+	assert.Contains(t, sixel, "#180!256N\x1b\\")
+}
+
+func TestTextPreview(t *testing.T) {
+	img := testImage(2, 1, func(x, _ int) [4]uint8 { return [2][4]uint8{{255, 0, 0, 255}, {0, 0, 255, 255}}[x] })
+
+	var out bytes.Buffer
+	require.NoError(t, writePreview(&out, previewText, img))
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 12, "48x24 pixels, two per line")
+	assert.Contains(t, lines[0], "\x1b[38;2;255;0;0m\x1b[48;2;255;0;0m▀")
+	assert.True(t, strings.HasSuffix(lines[0], "\x1b[38;2;0;0;255m\x1b[48;2;0;0;255m▀\x1b[0m"))
+}
+
+func TestInspectTUIPreview(t *testing.T) {
+	path := fixturePath(t, webpvalidator.FixtureAnimated)
+
+	code, stdout, _ := runCLIWithInput("f\nj\nq\n", "inspect", "-tui", "-preview", "text", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, 2*16, strings.Count(stdout, "▀\x1b[0m\n"), "a 32x32 preview after each of f and j")
+
+	code, stdout, _ = runCLIWithInput("f\nq\n", "inspect", "-tui", path)
+	assert.Equal(t, exitOK, code)
+	assert.NotContains(t, stdout, "\x1b[", "no previews when stdout is not a terminal")
+
+	code, _, stderr := runCLIWithInput("q\n", "inspect", "-tui", "-preview", "ascii", path)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, `invalid -preview "ascii"`)
+}
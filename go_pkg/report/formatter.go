@@ -0,0 +1,42 @@
+package report
+
+// Formatter writes the results of a webp-validator command in one output
+// format. Begin is called once with the command name, then Finding for
+// every finding in path order, then End, even when there are no findings.
+//
+// The CLI ships text, json and jsonl formatters and runs any other format
+// as a subprocess plugin speaking the same calls as JSON lines; see the
+// README.
+type Formatter interface {
+	Begin(command string) error
+	Finding(path string, f Finding) error
+	End() error
+}
+
+// TableFormatter is a Formatter that can also write a table, which is what
+// commands such as export produce instead of findings. Columns is called
+// once after Begin, then Row once per table row, then End.
+type TableFormatter interface {
+	Formatter
+	Columns(columns []Column) error
+	// Row receives one value per column, of the Go type matching the
+	// column's Type.
+	Row(values []any) error
+}
+
+// ColumnType is the type of the values of a table column.
+type ColumnType string
+
+// Column types, with the Go type of their values.
+const (
+	ColumnString ColumnType = "string" // string
+	ColumnBool   ColumnType = "bool"   // bool
+	ColumnInt    ColumnType = "int"    // int64
+	ColumnFloat  ColumnType = "float"  // float64
+)
+
+// Column describes one column of a table.
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+}
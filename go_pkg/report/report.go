@@ -0,0 +1,110 @@
+// Package report defines the stable JSON document printed by the verdict
+// command, and a visitor API for traversing it.
+//
+// The document only ever gains fields; Version is bumped for incompatible
+// changes. Tools built on this package should use Parse and Walk rather
+// than decoding the JSON ad hoc, so they keep working as the schema grows.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the schema version this package produces and understands.
+const Version = 1
+
+// Report is the verdict for a single file.
+// Every field is always present so consumers can rely on the shape.
+type Report struct {
+	Version  int       `json:"version"`
+	Path     string    `json:"path"`
+	Size     int       `json:"size"`
+	Valid    bool      `json:"valid"`
+	Partial  bool      `json:"partial"`
+	Error    string    `json:"error"`
+	Info     Info      `json:"info"`
+	Chunks   []Chunk   `json:"chunks"`
+	Frames   []Frame   `json:"frames"`
+	Findings []Finding `json:"findings"`
+	// Renders holds the verdicts of external renderers, when a renderer
+	// check was run; see Render.
+	Renders []Render `json:"renders"`
+}
+
+// Info is the image metadata. For files that failed validation it holds
+// whatever could be parsed if Report.Partial is set, and zeros otherwise.
+type Info struct {
+	Width      uint32 `json:"width"`
+	Height     uint32 `json:"height"`
+	HasAlpha   bool   `json:"has_alpha"`
+	IsAnimated bool   `json:"is_animated"`
+	NumFrames  uint32 `json:"num_frames"`
+}
+
+// Chunk covers the whole chunk, header and padding included, in
+// Offset/Length, and just its payload in PayloadOffset/PayloadLength.
+// Chunks nested in an ANMF frame have Depth 1 and follow their frame.
+type Chunk struct {
+	FourCC        string `json:"fourcc"`
+	Offset        uint64 `json:"offset"`
+	Length        uint64 `json:"length"`
+	PayloadOffset uint64 `json:"payload_offset"`
+	PayloadLength uint32 `json:"payload_length"`
+	Depth         uint32 `json:"depth"`
+}
+
+// Frame is the header of an ANMF animation frame. Offset is that of its
+// ANMF chunk.
+type Frame struct {
+	Index               int    `json:"index"`
+	Offset              uint64 `json:"offset"`
+	X                   uint32 `json:"x"`
+	Y                   uint32 `json:"y"`
+	Width               uint32 `json:"width"`
+	Height              uint32 `json:"height"`
+	DurationMs          uint32 `json:"duration_ms"`
+	Blend               bool   `json:"blend"`
+	DisposeToBackground bool   `json:"dispose_to_background"`
+}
+
+// Severity of a Finding.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is a problem with the file. Offset and Length are nil when the
+// problem cannot be attributed to a byte range, e.g. a decoder error.
+type Finding struct {
+	Severity string  `json:"severity"`
+	Message  string  `json:"message"`
+	Offset   *uint64 `json:"offset"`
+	Length   *uint64 `json:"length"`
+}
+
+// Render is the verdict of an external renderer, such as a headless
+// browser, on whether it can display the file. Renderer names it; Width
+// and Height are what it rendered, zero if it failed, and Message is its
+// explanation, if any. Disagreements with the validator also appear as
+// findings.
+type Render struct {
+	Renderer string `json:"renderer"`
+	Rendered bool   `json:"rendered"`
+	Width    uint32 `json:"width"`
+	Height   uint32 `json:"height"`
+	Message  string `json:"message"`
+}
+
+// Parse decodes a report, rejecting schema versions newer than Version.
+// Unknown fields are ignored.
+func Parse(data []byte) (Report, error) {
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Report{}, fmt.Errorf("invalid report: %w", err)
+	}
+	if r.Version > Version {
+		return Report{}, fmt.Errorf("unsupported report version %d (max %d)", r.Version, Version)
+	}
+	return r, nil
+}
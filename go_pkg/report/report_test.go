@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleReport() Report {
+	offset, length := uint64(4), uint64(4)
+	return Report{
+		Version: Version,
+		Path:    "sample.webp",
+		Info:    Info{Width: 10, Height: 20, IsAnimated: true, NumFrames: 2},
+		Chunks: []Chunk{
+			{FourCC: "VP8X", Offset: 12},
+			{FourCC: "ANMF", Offset: 30},
+			{FourCC: "VP8L", Offset: 54, Depth: 1},
+			{FourCC: "ANMF", Offset: 100},
+			{FourCC: "VP8L", Offset: 124, Depth: 1},
+		},
+		Frames: []Frame{{Index: 0, Offset: 30}, {Index: 1, Offset: 100}},
+		Findings: []Finding{
+			{Severity: SeverityWarning, Message: "trailing data"},
+			{Severity: SeverityError, Message: "bad size", Offset: &offset, Length: &length},
+		},
+		Renders: []Render{{Renderer: "chromium", Rendered: true, Width: 10, Height: 20}},
+	}
+}
+
+// describe renders a node for comparison.
+func describe(n Node) string {
+	switch n := n.(type) {
+	case *Info:
+		return fmt.Sprintf("info %dx%d", n.Width, n.Height)
+	case *Chunk:
+		return fmt.Sprintf("chunk %s@%d", n.FourCC, n.Offset)
+	case *Frame:
+		return fmt.Sprintf("frame %d", n.Index)
+	case *Finding:
+		return "finding " + n.Message
+	case *Render:
+		return "render " + n.Renderer
+	default:
+		return "unknown"
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	var visited []string
+	err := Walk(sampleReport(), VisitorFunc(func(n Node) error {
+		visited = append(visited, describe(n))
+		return nil
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"info 10x20",
+		"chunk VP8X@12", "chunk ANMF@30", "chunk VP8L@54", "chunk ANMF@100", "chunk VP8L@124",
+		"frame 0", "frame 1",
+		"finding trailing data", "finding bad size",
+		"render chromium",
+	}, visited)
+}
+
+func TestWalkSkipChildren(t *testing.T) {
+	var chunks []string
+	err := Walk(sampleReport(), VisitorFunc(func(n Node) error {
+		if c, ok := n.(*Chunk); ok {
+			chunks = append(chunks, describe(c))
+			if c.FourCC == "ANMF" && c.Offset == 30 {
+				return SkipChildren
+			}
+		}
+		return SkipChildren
+	}))
+	require.NoError(t, err, "SkipChildren should never be returned by Walk")
+
+	assert.Equal(t, []string{"chunk VP8X@12", "chunk ANMF@30", "chunk ANMF@100"}, chunks)
+}
+
+func TestWalkStop(t *testing.T) {
+	stop := errors.New("stop")
+	var count int
+	err := Walk(sampleReport(), VisitorFunc(func(n Node) error {
+		count++
+		if _, ok := n.(*Frame); ok {
+			return stop
+		}
+		return nil
+	}))
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 7, count, "walk should stop at the first frame")
+}
+
+func TestParse(t *testing.T) {
+	data, err := json.Marshal(sampleReport())
+	require.NoError(t, err)
+
+	r, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, sampleReport(), r)
+
+	// Fields added by newer minor revisions are ignored.
+	_, err = Parse([]byte(`{"version": 1, "future_field": {"a": 1}}`))
+	assert.NoError(t, err)
+
+	_, err = Parse([]byte(`{"version": 2}`))
+	assert.ErrorContains(t, err, "unsupported report version 2")
+
+	_, err = Parse([]byte(`not json`))
+	assert.ErrorContains(t, err, "invalid report")
+}
@@ -0,0 +1,90 @@
+package report
+
+import "errors"
+
+// Node is a value visited by Walk: *Info, *Chunk, *Frame, *Finding or
+// *Render.
+// More node types may be added; visitors should ignore types they do not
+// know.
+type Node interface {
+	node()
+}
+
+func (*Info) node()    {}
+func (*Chunk) node()   {}
+func (*Frame) node()   {}
+func (*Finding) node() {}
+func (*Render) node()  {}
+
+// Visitor is called for every node of a report.
+type Visitor interface {
+	// Visit is called with each node. Returning SkipChildren from a
+	// *Chunk skips the chunks nested in it; any other non-nil error
+	// stops the walk and is returned by Walk.
+	Visit(n Node) error
+}
+
+// VisitorFunc adapts a function to a Visitor.
+type VisitorFunc func(n Node) error
+
+// Visit calls f(n).
+func (f VisitorFunc) Visit(n Node) error {
+	return f(n)
+}
+
+// SkipChildren is returned by a Visitor to skip the chunks nested in the
+// chunk being visited. It is not returned by Walk.
+var SkipChildren = errors.New("skip children")
+
+// Walk visits r.Info, then every chunk in file order (nested chunks after
+// the chunk containing them), then every frame, then every finding, then
+// every renderer verdict.
+// Nodes must be treated as read-only.
+func Walk(r Report, v Visitor) error {
+	if err := visit(v, &r.Info); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(r.Chunks); i++ {
+		chunk := &r.Chunks[i]
+		err := visit(v, chunk)
+		if errors.Is(err, SkipChildren) {
+			for i+1 < len(r.Chunks) && r.Chunks[i+1].Depth > chunk.Depth {
+				i++
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range r.Frames {
+		if err := visit(v, &r.Frames[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range r.Findings {
+		if err := visit(v, &r.Findings[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range r.Renders {
+		if err := visit(v, &r.Renders[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visit calls v, treating SkipChildren as nil for nodes without children.
+func visit(v Visitor, n Node) error {
+	err := v.Visit(n)
+	if _, ok := n.(*Chunk); !ok && errors.Is(err, SkipChildren) {
+		return nil
+	}
+	return err
+}
@@ -1,131 +0,0 @@
-package main
-
-import (
-	"bytes"
-	"fmt"
-	"image"
-	_ "image/gif"
-	_ "image/jpeg"
-	_ "image/png"
-	"os"
-	"testing"
-
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	_ "golang.org/x/image/webp"
-)
-
-// ValidateWebpByStdLib validates WebP using Go standard library.
-// Note: Go stdlib's webp package does not support animated WebP.
-func ValidateWebpByStdLib(path string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
-	_, _, err = image.Decode(bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("invalid image file: %w", err)
-	}
-
-	return nil
-}
-
-func TestValidateStaticWebp(t *testing.T) {
-	data, err := os.ReadFile("../images/static.webp")
-	require.NoError(t, err)
-	info := ValidateWebp(data)
-
-	assert.True(t, info.IsValid, "static webp should be valid")
-	assert.False(t, info.IsAnimated, "static webp should not be animated")
-	assert.Equal(t, uint32(0), info.NumFrames, "static webp should have 0 frames")
-	assert.Greater(t, info.Width, uint32(0), "width should be greater than 0")
-	assert.Greater(t, info.Height, uint32(0), "height should be greater than 0")
-
-	t.Logf("static webp validated successfully: %dx%d", info.Width, info.Height)
-}
-
-func TestValidateDynamicWebp(t *testing.T) {
-	data, err := os.ReadFile("../images/dynamic.webp")
-	require.NoError(t, err)
-	info := ValidateWebp(data)
-
-	assert.True(t, info.IsValid, "dynamic webp should be valid")
-	assert.True(t, info.IsAnimated, "dynamic webp should be animated")
-	assert.Greater(t, info.NumFrames, uint32(1), "dynamic webp should have multiple frames")
-	assert.Greater(t, info.Width, uint32(0), "width should be greater than 0")
-	assert.Greater(t, info.Height, uint32(0), "height should be greater than 0")
-
-	t.Logf("dynamic webp validated successfully: %dx%d, %d frames", info.Width, info.Height, info.NumFrames)
-}
-
-func TestValidateFakeWebp(t *testing.T) {
-	data, err := os.ReadFile("../images/fake.webp")
-	require.NoError(t, err)
-	info := ValidateWebp(data)
-
-	assert.False(t, info.IsValid, "fake webp should be invalid")
-	assert.NotEmpty(t, info.Error, "fake webp should have error message")
-	assert.Contains(t, info.Error, "webp format validation failed", "error should indicate validation failure")
-
-	t.Logf("fake webp correctly rejected: %s", info.Error)
-}
-
-func TestValidateNonexistentFile(t *testing.T) {
-	// For nonexistent file, we can't read it, so we can't pass bytes.
-	// The Rust side no longer handles file opening, so this test case changes.
-	// We should test that ValidateWebp handles empty or invalid data gracefully if we want.
-	// But strictly speaking, the responsibility of opening the file is now on the caller (Go side).
-	// So we can test what happens if we pass empty bytes or garbage bytes.
-
-	// Let's test empty bytes
-	info := ValidateWebp([]byte{})
-	assert.False(t, info.IsValid, "empty data should be invalid")
-	assert.NotEmpty(t, info.Error, "empty data should have error message")
-
-	t.Logf("empty data correctly handled: %s", info.Error)
-}
-
-// TestCompareWithStdLib demonstrates that Go stdlib cannot handle animated WebP.
-func TestCompareWithStdLib(t *testing.T) {
-	dynamicWebpPath := "../images/dynamic.webp"
-
-	// Validate using Rust library
-	data, err := os.ReadFile(dynamicWebpPath)
-	require.NoError(t, err)
-	rustResult := ValidateWebp(data)
-	require.True(t, rustResult.IsValid, "rust library should validate dynamic webp")
-	require.True(t, rustResult.IsAnimated, "rust library should detect animation")
-	require.Greater(t, rustResult.NumFrames, uint32(1), "rust library should detect multiple frames")
-	t.Logf("rust library result: valid=%v, animated=%v, frames=%d",
-		rustResult.IsValid, rustResult.IsAnimated, rustResult.NumFrames)
-
-	// Validate using Go standard library
-	stdlibErr := ValidateWebpByStdLib(dynamicWebpPath)
-	assert.Error(t, stdlibErr, "golang stdlib should fail on animated webp")
-	if stdlibErr != nil {
-		t.Logf("golang stdlib result: error (expected) - %v", stdlibErr)
-		t.Logf("this proves golang stdlib cannot handle animated webp files")
-	}
-
-	t.Log("Conclusion: Rust library has full WebP support including animation")
-	t.Log("Go stdlib lacks animated WebP support")
-}
-
-// BenchmarkValidateWebp measures performance of Rust library validation
-func BenchmarkValidateWebp(b *testing.B) {
-	data, err := os.ReadFile("../images/static.webp")
-	if err != nil {
-		b.Fatal(err)
-	}
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		ValidateWebp(data)
-	}
-}
-
-func BenchmarkValidateWebpStdLib(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		ValidateWebpByStdLib("../images/static.webp")
-	}
-}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"webpValidatorTest/webpvalidator"
+)
+
+func runVerdict(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verdict", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	compact := flags.Bool("compact", false, "print the verdict on a single line")
+	renderer := flags.String("renderer", "", "URL of a renderer sidecar to cross-check borderline files with")
+	renderAll := flags.Bool("render-all", false, "with -renderer, check every file, not only borderline ones")
+	renderTimeout := flags.Duration("renderer-timeout", 30*time.Second, "how long to wait for the renderer")
+	format := formatFlag(flags, "")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: webp-validator verdict [-compact] [-renderer url [-render-all] [-renderer-timeout d]] [-format name] file.webp")
+		fmt.Fprintln(stderr, "\nprints a JSON verdict locating every chunk and finding by byte range")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	path := positional[0]
+	data, snapshot, err := webpvalidator.ReadWebpFile(path, nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "verdict: %v\n", err)
+		return exitError
+	}
+
+	v := webpvalidator.Verdict(path, data)
+	if err := snapshot.Verify(); err != nil {
+		fmt.Fprintf(stderr, "verdict: %v\n", err)
+		return exitError
+	}
+	if *renderer != "" && (*renderAll || webpvalidator.Borderline(v)) {
+		ctx, cancel := context.WithTimeout(context.Background(), *renderTimeout)
+		err := webpvalidator.CheckRender(ctx, &v, data, &webpvalidator.HTTPRenderer{URL: *renderer})
+		cancel()
+		if err != nil {
+			fmt.Fprintf(stderr, "verdict: %v\n", err)
+			return exitError
+		}
+	}
+	if *format != "" {
+		return writeFindings("verdict", *format, reportFindings(v), stdout, stderr)
+	}
+	encoder := json.NewEncoder(stdout)
+	if !*compact {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(stderr, "verdict: %v\n", err)
+		return exitError
+	}
+
+	if !v.Valid {
+		return exitFindings
+	}
+	return exitOK
+}
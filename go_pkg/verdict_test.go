@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"webpValidatorTest/report"
+	"webpValidatorTest/webpvalidator"
+)
+
+// renderSidecar answers like a renderer that renders everything at 1x1,
+// and records the bodies it was sent.
+func renderSidecar(t *testing.T, received *[][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "image/webp", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		*received = append(*received, body)
+		json.NewEncoder(w).Encode(map[string]any{"rendered": true, "width": 1, "height": 1, "engine": "test"})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerdictRenderer(t *testing.T) {
+	var received [][]byte
+	server := renderSidecar(t, &received)
+	sample := fixtureData(t, webpvalidator.FixtureStatic)
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.webp")
+	trailing := filepath.Join(dir, "trailing.webp")
+	require.NoError(t, os.WriteFile(clean, sample, 0o644))
+	require.NoError(t, os.WriteFile(trailing, append(append([]byte(nil), sample...), "trailing"...), 0o644))
+
+	code, stdout, stderr := runCLIForTest("verdict", "-renderer", server.URL, clean)
+	require.Equal(t, exitOK, code, stderr)
+	v, err := report.Parse([]byte(stdout))
+	require.NoError(t, err)
+	assert.Empty(t, v.Renders, "clean files are not borderline")
+	assert.Empty(t, received)
+
+	code, stdout, stderr = runCLIForTest("verdict", "-renderer", server.URL, trailing)
+	require.Equal(t, exitOK, code, stderr)
+	v, err = report.Parse([]byte(stdout))
+	require.NoError(t, err)
+	require.Len(t, v.Renders, 1)
+	assert.True(t, v.Renders[0].Rendered)
+	assert.Len(t, received, 1)
+
+	code, _, stderr = runCLIForTest("verdict", "-renderer", server.URL, "-render-all", clean)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Len(t, received, 2)
+
+	server.Close()
+	code, _, stderr = runCLIForTest("verdict", "-renderer", server.URL, trailing)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "renderer check failed")
+}
+
+func TestCLIVerdictCacheEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verdicts")
+	t.Setenv(webpvalidator.VerdictCacheEnv, path)
+
+	before := webpvalidator.Stats()
+	for i := 0; i < 2; i++ {
+		code, _, stderr := runCLIForTest("verdict", fixturePath(t, webpvalidator.FixtureStatic))
+		require.Equal(t, exitOK, code, stderr)
+	}
+	assert.Equal(t, uint64(1), webpvalidator.Stats().VerdictCacheHits-before.VerdictCacheHits, "the second run hits the file")
+	assert.Nil(t, webpvalidator.SetBackend(nil), "the backend is restored after the command")
+	assert.FileExists(t, path)
+
+	t.Setenv(webpvalidator.VerdictCacheEnv, filepath.Join(t.TempDir(), "missing", "verdicts"))
+	code, _, stderr := runCLIForTest("verdict", fixturePath(t, webpvalidator.FixtureStatic))
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "failed to open verdict cache")
+}
@@ -0,0 +1,147 @@
+// Package webptmpl provides html/template functions that emit image
+// dimensions and placeholders, so server-rendered pages reserve the right
+// space for every image and do not shift as images load:
+//
+//	<img src="/img/{{.Hero}}" {{webpDims .Hero}} alt="">
+//	<div style="aspect-ratio: {{webpAspect .Hero}}" data-blurhash="{{webpPlaceholder .Hero}}"></div>
+//
+// Images are loaded through a Loader once per name and cached, so renders
+// after the first touch neither the disk nor the validator.
+package webptmpl
+
+import (
+	"fmt"
+	"html/template"
+	"sync"
+)
+
+// Image is what the template functions know about an image.
+type Image struct {
+	Width  uint32
+	Height uint32
+	// Placeholder is a BlurHash of the image, or empty if unavailable.
+	Placeholder string
+}
+
+// Loader validates the image a template refers to by name and describes
+// it. Names are whatever the templates pass, typically paths relative to
+// an asset directory.
+type Loader func(name string) (Image, error)
+
+// Funcs caches images by name for the template functions. It is safe for
+// concurrent use by any number of templates.
+type Funcs struct {
+	load Loader
+
+	mu     sync.Mutex
+	images map[string]*cachedImage
+}
+
+type cachedImage struct {
+	ready chan struct{}
+	image Image
+	err   error
+}
+
+// New returns Funcs loading images with load.
+func New(load Loader) *Funcs {
+	return &Funcs{load: load, images: make(map[string]*cachedImage)}
+}
+
+// FuncMap returns webpDims, webpAspect and webpPlaceholder, for
+// template.Funcs.
+func (f *Funcs) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"webpDims":        f.Dims,
+		"webpAspect":      f.Aspect,
+		"webpPlaceholder": f.Placeholder,
+	}
+}
+
+// Image returns the cached description of name, loading it on first use.
+// Concurrent first uses share one load. Failed loads are not cached, so a
+// fixed file is picked up by the next render; a Loader that panics fails
+// the load with an error.
+func (f *Funcs) Image(name string) (Image, error) {
+	f.mu.Lock()
+	cached, ok := f.images[name]
+	if !ok {
+		cached = &cachedImage{ready: make(chan struct{})}
+		f.images[name] = cached
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		f.fill(name, cached)
+	}
+	<-cached.ready
+	return cached.image, cached.err
+}
+
+// fill loads name into cached and wakes its waiters, whatever the Loader
+// does.
+func (f *Funcs) fill(name string, cached *cachedImage) {
+	defer func() {
+		if r := recover(); r != nil {
+			cached.image, cached.err = Image{}, fmt.Errorf("loading %s panicked: %v", name, r)
+		}
+		if cached.err != nil {
+			f.mu.Lock()
+			if f.images[name] == cached {
+				delete(f.images, name)
+			}
+			f.mu.Unlock()
+		}
+		close(cached.ready)
+	}()
+	cached.image, cached.err = f.load(name)
+}
+
+// Forget drops name from the cache, for when the image is replaced.
+func (f *Funcs) Forget(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.images, name)
+}
+
+// Dims returns the width and height attributes of an img tag.
+func (f *Funcs) Dims(name string) (template.HTMLAttr, error) {
+	img, err := f.Image(name)
+	if err != nil {
+		return "", err
+	}
+	return template.HTMLAttr(fmt.Sprintf(`width="%d" height="%d"`, img.Width, img.Height)), nil
+}
+
+// Aspect returns a CSS aspect-ratio value such as "16 / 9", reduced to
+// lowest terms.
+func (f *Funcs) Aspect(name string) (template.CSS, error) {
+	img, err := f.Image(name)
+	if err != nil {
+		return "", err
+	}
+	if img.Width == 0 || img.Height == 0 {
+		return "", fmt.Errorf("webpAspect %s: image has no area", name)
+	}
+	d := gcd(img.Width, img.Height)
+	return template.CSS(fmt.Sprintf("%d / %d", img.Width/d, img.Height/d)), nil
+}
+
+// Placeholder returns the image's BlurHash.
+func (f *Funcs) Placeholder(name string) (string, error) {
+	img, err := f.Image(name)
+	if err != nil {
+		return "", err
+	}
+	if img.Placeholder == "" {
+		return "", fmt.Errorf("webpPlaceholder %s: no placeholder available", name)
+	}
+	return img.Placeholder, nil
+}
+
+func gcd(a, b uint32) uint32 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,126 @@
+package webptmpl
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, funcs *Funcs, text string, data any) (string, error) {
+	t.Helper()
+	tmpl := template.Must(template.New("page").Funcs(funcs.FuncMap()).Parse(text))
+	var out strings.Builder
+	err := tmpl.Execute(&out, data)
+	return out.String(), err
+}
+
+func TestFuncs(t *testing.T) {
+	funcs := New(func(name string) (Image, error) {
+		return Image{Width: 1920, Height: 1080, Placeholder: "LKO2?U%2Tw=w]~RBVZRi};RPxuwH"}, nil
+	})
+
+	out, err := render(t, funcs,
+		`<img src="/img/{{.}}" {{webpDims .}}><div style="aspect-ratio: {{webpAspect .}}" data-blurhash="{{webpPlaceholder .}}"></div>`,
+		"hero.webp")
+	require.NoError(t, err)
+	assert.Equal(t,
+		`<img src="/img/hero.webp" width="1920" height="1080"><div style="aspect-ratio: 16 / 9" data-blurhash="LKO2?U%2Tw=w]~RBVZRi};RPxuwH"></div>`,
+		out)
+}
+
+func TestFuncsCache(t *testing.T) {
+	var loads atomic.Int32
+	funcs := New(func(name string) (Image, error) {
+		loads.Add(1)
+		return Image{Width: 3, Height: 2}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dims, err := funcs.Dims("a.webp")
+			assert.NoError(t, err)
+			assert.Equal(t, template.HTMLAttr(`width="3" height="2"`), dims)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load(), "concurrent first renders share one load")
+
+	funcs.Forget("a.webp")
+	_, err := funcs.Aspect("a.webp")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+func TestFuncsErrors(t *testing.T) {
+	fail := true
+	funcs := New(func(name string) (Image, error) {
+		if fail {
+			return Image{}, errors.New(name + ": invalid webp")
+		}
+		return Image{Width: 1, Height: 1}, nil
+	})
+
+	_, err := render(t, funcs, `{{webpDims .}}`, "bad.webp")
+	assert.ErrorContains(t, err, "bad.webp: invalid webp")
+
+	fail = false
+	_, err = funcs.Dims("bad.webp")
+	assert.NoError(t, err, "failed loads are retried")
+
+	_, err = funcs.Placeholder("bad.webp")
+	assert.ErrorContains(t, err, "no placeholder available")
+
+	_, err = New(func(string) (Image, error) { return Image{}, nil }).Aspect("empty.webp")
+	assert.ErrorContains(t, err, "no area")
+}
+
+func TestFuncsLoaderPanic(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int32
+	funcs := New(func(name string) (Image, error) {
+		if loads.Add(1) == 1 {
+			<-release
+			panic("decoder exploded")
+		}
+		return Image{Width: 1, Height: 1}, nil
+	})
+
+	// Renders waiting on the panicking load must be woken, not left
+	// blocked. Those that arrive after it failed load again.
+	results := make(chan error, 4)
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			_, err := funcs.Dims("a.webp")
+			results <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	panicked := 0
+	for i := 0; i < cap(results); i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				assert.EqualError(t, err, "loading a.webp panicked: decoder exploded")
+				panicked++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("render blocked on a panicked load")
+		}
+	}
+	assert.GreaterOrEqual(t, panicked, 1)
+
+	_, err := funcs.Dims("a.webp")
+	assert.NoError(t, err, "a panicked load is retried")
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package webpvalidator
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// setThreadAffinity pins the calling OS thread to cpus.
+func setThreadAffinity(cpus []int) error {
+	var mask [maxAffinityCPUs / 64]uint64
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= maxAffinityCPUs {
+			return fmt.Errorf("cpu %d out of range", cpu)
+		}
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+
+	// pid 0 is the calling thread.
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package webpvalidator
+
+import (
+	"fmt"
+	"math/bits"
+	"syscall"
+)
+
+var (
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	procGetCurrentThread      = kernel32.NewProc("GetCurrentThread")
+	procSetThreadAffinityMask = kernel32.NewProc("SetThreadAffinityMask")
+)
+
+// setThreadAffinity pins the calling OS thread to cpus. Only the first
+// processor group is supported, and only as many CPUs as the mask has
+// bits: 0-63, or 0-31 on 32-bit Windows.
+func setThreadAffinity(cpus []int) error {
+	var mask uintptr
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= bits.UintSize {
+			return fmt.Errorf("cpu %d out of range", cpu)
+		}
+		mask |= 1 << cpu
+	}
+
+	thread, _, _ := procGetCurrentThread.Call()
+	previous, _, err := procSetThreadAffinityMask.Call(thread, mask)
+	if previous == 0 {
+		return err
+	}
+	return nil
+}
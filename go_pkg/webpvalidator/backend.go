@@ -0,0 +1,54 @@
+package webpvalidator
+
+import "sync/atomic"
+
+// Backend answers ValidateWebp, InspectWebp, SupportedFeatures,
+// LibraryVersion and the pixel decoding behind Decoded. The default is the
+// native library; SetBackend swaps in another implementation, such as a
+// Replay for tests that must run without the library installed.
+type Backend interface {
+	Validate(data []byte) WebpInfo
+	Inspect(data []byte) WebpInspection
+	// Decode fills pixels and durations with the leading len(durations)
+	// frames of data as RGBA, sized from a successful Validate (see
+	// decode_webp_ffi).
+	Decode(data, pixels []byte, durations []uint32) error
+	SupportedFeatures() (Features, error)
+	Version() (string, error)
+}
+
+// NativeBackend calls the native library directly, bypassing SetBackend.
+// It is what a Recorder usually wraps.
+var NativeBackend Backend = nativeBackend{}
+
+type nativeBackend struct{}
+
+func (nativeBackend) Validate(data []byte) WebpInfo        { return validateNative(data) }
+func (nativeBackend) Inspect(data []byte) WebpInspection   { return inspectNative(data) }
+func (nativeBackend) SupportedFeatures() (Features, error) { return supportedFeaturesNative() }
+func (nativeBackend) Version() (string, error)             { return versionNative() }
+
+func (nativeBackend) Decode(data, pixels []byte, durations []uint32) error {
+	return decodeNative(data, pixels, durations)
+}
+
+// backendHolder gives atomic.Value a single concrete type to store.
+type backendHolder struct{ backend Backend }
+
+var activeBackend atomic.Value
+
+// SetBackend routes every subsequent ValidateWebp, InspectWebp,
+// SupportedFeatures, LibraryVersion and Decoded call, including those
+// made by the CLI commands and Pool, to b. A nil b restores the native
+// library. It returns the previous backend so tests can restore it.
+func SetBackend(b Backend) Backend {
+	previous, _ := activeBackend.Swap(backendHolder{b}).(backendHolder)
+	return previous.backend
+}
+
+// currentBackend returns the backend set with SetBackend, or nil for the
+// native library.
+func currentBackend() Backend {
+	holder, _ := activeBackend.Load().(backendHolder)
+	return holder.backend
+}
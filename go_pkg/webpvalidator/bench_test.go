@@ -0,0 +1,77 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// benchImages are the inputs every benchmark in this file runs against.
+var benchImages = []struct {
+	name     string
+	fixture  string
+	animated bool
+}{
+	{"static", FixtureStatic, false},
+	{"dynamic", FixtureAnimated, true},
+}
+
+// BenchmarkInputModes compares the overhead of each input modality
+// (bytes, path, reader) for each backend (rust ffi, go stdlib).
+//
+// Run with:
+//
+//	go test -run '^$' -bench BenchmarkInputModes -benchmem
+func BenchmarkInputModes(b *testing.B) {
+	for _, img := range benchImages {
+		path := mustFixturePath(b, img.fixture)
+		data := mustFixtureData(b, img.fixture)
+
+		b.Run("rust/bytes/"+img.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ValidateWebp(data)
+			}
+		})
+
+		b.Run("rust/path/"+img.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ValidateWebpFile(path)
+			}
+		})
+
+		b.Run("rust/reader/"+img.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ValidateWebpReader(bytes.NewReader(data))
+			}
+		})
+
+		// Go stdlib cannot decode animated webp, so timings would only
+		// measure how fast it fails. It also has no byte API: image.Decode
+		// always consumes a reader.
+		if img.animated {
+			continue
+		}
+
+		b.Run("stdlib/path/"+img.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ValidateWebpByStdLib(path)
+			}
+		})
+
+		b.Run("stdlib/reader/"+img.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				image.Decode(bytes.NewReader(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidWebpBlob is wrapped by every error WebpBlob returns for
+// contents that are not a webp image or that break its BlobPolicy.
+var ErrInvalidWebpBlob = errors.New("invalid webp blob")
+
+// BlobPolicy limits what a WebpBlob accepts. Zero fields are not enforced,
+// so the zero policy accepts any valid webp image but not NULL.
+type BlobPolicy struct {
+	MaxWidth       uint32
+	MaxHeight      uint32
+	MaxBytes       int64
+	MaxFrames      uint32
+	RejectAnimated bool
+	RequireAlpha   bool
+	// AllowNull lets a NULL column scan into a blob with Null set instead
+	// of failing.
+	AllowNull bool
+}
+
+// DefaultBlobPolicy applies to WebpBlob values without a Policy. Set it
+// during initialization, before any scans.
+var DefaultBlobPolicy BlobPolicy
+
+// assetPolicy expresses p as the AssetPolicy lintrepo enforces, so both
+// report violations the same way.
+func (p BlobPolicy) assetPolicy() AssetPolicy {
+	var policy AssetPolicy
+	if p.MaxWidth > 0 {
+		policy.MaxWidth = &p.MaxWidth
+	}
+	if p.MaxHeight > 0 {
+		policy.MaxHeight = &p.MaxHeight
+	}
+	if p.MaxBytes > 0 {
+		policy.MaxBytes = &p.MaxBytes
+	}
+	if p.MaxFrames > 0 {
+		policy.MaxFrames = &p.MaxFrames
+	}
+	if p.RejectAnimated {
+		allow := false
+		policy.AllowAnimated = &allow
+	}
+	if p.RequireAlpha {
+		policy.RequireAlpha = &p.RequireAlpha
+	}
+	return policy
+}
+
+// WebpBlob is a webp image stored in a BLOB column. It implements
+// sql.Scanner and driver.Valuer, validating on the way in and out, so a
+// scanned WebpBlob always holds a valid image that satisfies its policy:
+//
+//	avatar := WebpBlob{Policy: &BlobPolicy{MaxWidth: 512, MaxHeight: 512}}
+//	err := db.QueryRow("SELECT avatar FROM users WHERE id = ?", id).Scan(&avatar)
+//	if errors.Is(err, ErrInvalidWebpBlob) {
+//	    // avatar.Info says what was wrong with the stored bytes
+//	}
+type WebpBlob struct {
+	// Data is the image, owned by the blob: Scan copies it out of the
+	// driver's buffer.
+	Data []byte
+	// Info is the validation result for Data. After a failed Scan it
+	// describes the rejected contents.
+	Info WebpInfo
+	// Null reports a NULL column, accepted only with AllowNull.
+	Null bool
+	// Policy overrides DefaultBlobPolicy for this blob.
+	Policy *BlobPolicy
+}
+
+func (b *WebpBlob) policy() BlobPolicy {
+	if b.Policy != nil {
+		return *b.Policy
+	}
+	return DefaultBlobPolicy
+}
+
+// Scan implements sql.Scanner for []byte, string and NULL column values.
+// When it returns an error, Data is nil.
+func (b *WebpBlob) Scan(src any) error {
+	b.Data, b.Info, b.Null = nil, WebpInfo{}, false
+
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		if !b.policy().AllowNull {
+			return fmt.Errorf("%w: column is NULL", ErrInvalidWebpBlob)
+		}
+		b.Null = true
+		return nil
+	case []byte:
+		data = bytes.Clone(src)
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into WebpBlob", src)
+	}
+
+	info, err := b.policy().check(data)
+	b.Info = info
+	if err != nil {
+		return err
+	}
+	b.Data = data
+	return nil
+}
+
+// Value implements driver.Valuer, refusing to write contents Scan would
+// reject.
+func (b WebpBlob) Value() (driver.Value, error) {
+	if b.Null {
+		if !b.policy().AllowNull {
+			return nil, fmt.Errorf("%w: NULL not allowed by policy", ErrInvalidWebpBlob)
+		}
+		return nil, nil
+	}
+	if _, err := b.policy().check(b.Data); err != nil {
+		return nil, err
+	}
+	return b.Data, nil
+}
+
+// check validates data and applies p to the result.
+func (p BlobPolicy) check(data []byte) (WebpInfo, error) {
+	info := ValidateWebp(data)
+	if !info.IsValid {
+		return info, fmt.Errorf("%w: %s", ErrInvalidWebpBlob, info.Error)
+	}
+	if violations := p.assetPolicy().Check(info, int64(len(data))); len(violations) > 0 {
+		return info, fmt.Errorf("%w: %s", ErrInvalidWebpBlob, strings.Join(violations, "; "))
+	}
+	return info, nil
+}
@@ -0,0 +1,79 @@
+package webpvalidator
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ sql.Scanner   = (*WebpBlob)(nil)
+	_ driver.Valuer = WebpBlob{}
+)
+
+func TestWebpBlobScan(t *testing.T) {
+	src := append([]byte(nil), sampleAlpha...)
+	var blob WebpBlob
+	require.NoError(t, blob.Scan(src))
+	assert.True(t, blob.Info.IsValid)
+	assert.True(t, blob.Info.HasAlpha)
+	assert.Equal(t, sampleAlpha, blob.Data)
+
+	// The driver may reuse its buffer after Scan returns.
+	src[0] = 0
+	assert.Equal(t, sampleAlpha, blob.Data)
+
+	require.NoError(t, blob.Scan(string(sampleLossy)))
+	assert.Equal(t, sampleLossy, blob.Data)
+
+	value, err := blob.Value()
+	require.NoError(t, err)
+	assert.Equal(t, driver.Value(sampleLossy), value)
+}
+
+func TestWebpBlobRejects(t *testing.T) {
+	var blob WebpBlob
+	err := blob.Scan([]byte("\x89PNG\r\n\x1a\n"))
+	assert.ErrorIs(t, err, ErrInvalidWebpBlob)
+	assert.ErrorContains(t, err, "webp format validation failed")
+	assert.Nil(t, blob.Data)
+	assert.False(t, blob.Info.IsValid)
+
+	assert.ErrorIs(t, blob.Scan(nil), ErrInvalidWebpBlob)
+	assert.ErrorContains(t, blob.Scan(42), "cannot scan int into WebpBlob")
+
+	_, err = WebpBlob{Data: []byte("RIFF")}.Value()
+	assert.ErrorIs(t, err, ErrInvalidWebpBlob)
+}
+
+func TestWebpBlobPolicy(t *testing.T) {
+	blob := WebpBlob{Policy: &BlobPolicy{RequireAlpha: true, MaxBytes: 10}}
+	err := blob.Scan(sampleLossy)
+	assert.ErrorIs(t, err, ErrInvalidWebpBlob)
+	assert.ErrorContains(t, err, "exceeds policy max_bytes 10; policy requires an alpha channel")
+	assert.True(t, blob.Info.IsValid, "Info describes the rejected image")
+
+	blob = WebpBlob{Policy: &BlobPolicy{RejectAnimated: true}}
+	assert.ErrorContains(t, blob.Scan(sampleAnimated), "animated webp not allowed by policy")
+	assert.NoError(t, blob.Scan(sampleLossless))
+
+	blob = WebpBlob{Policy: &BlobPolicy{AllowNull: true}}
+	require.NoError(t, blob.Scan(nil))
+	assert.True(t, blob.Null)
+	assert.Nil(t, blob.Data)
+	value, err := blob.Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	previous := DefaultBlobPolicy
+	DefaultBlobPolicy = BlobPolicy{MaxWidth: 1, MaxHeight: 1, MaxFrames: 1}
+	defer func() { DefaultBlobPolicy = previous }()
+	var fromDefault WebpBlob
+	assert.NoError(t, fromDefault.Scan(sampleLossy))
+	_, err = WebpBlob{Data: sampleAnimated}.Value()
+	assert.NoError(t, err, "frame count is within max_frames 1")
+	assert.Error(t, (&WebpBlob{}).Scan(nil), "the default policy rejects NULL")
+}
@@ -0,0 +1,278 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+
+	"webpValidatorTest/report"
+)
+
+// ConformanceSuiteVersion identifies the vector set in certification
+// output; bump it whenever a vector or an expectation changes.
+const ConformanceSuiteVersion = 2
+
+// ConformanceClass is the kind of result a conformance vector exercises.
+type ConformanceClass string
+
+const (
+	// ConformanceValid vectors are valid and have no findings.
+	ConformanceValid ConformanceClass = "valid"
+	// ConformanceWarning vectors are valid with warning findings.
+	ConformanceWarning ConformanceClass = "warning"
+	// ConformanceInvalid vectors fail validation.
+	ConformanceInvalid ConformanceClass = "invalid"
+)
+
+// ConformanceVector is one input of the conformance suite and the verdict
+// report the project intends for it.
+type ConformanceVector struct {
+	Name        string
+	Description string
+	Class       ConformanceClass
+	Expect      ConformanceExpectation
+	build       func() []byte
+}
+
+// Data returns the vector's contents.
+func (v ConformanceVector) Data() []byte {
+	return v.build()
+}
+
+// ConformanceExpectation is the part of a verdict report a conforming
+// build must reproduce exactly.
+type ConformanceExpectation struct {
+	Valid     bool
+	Partial   bool
+	Width     uint32
+	Height    uint32
+	NumFrames uint32
+	Features  Features
+	// Decoded is the number of RGBA frames the decoder produces: 1 for a
+	// still image, NumFrames for an animation. Zero means decoding must
+	// fail, as it must for every invalid file.
+	Decoded int
+	// Chunks lists every chunk the walk finds, nested ones included, as
+	// FOURCC@offset in file order.
+	Chunks []string
+	// Findings lists the findings in order, as severity@offset+length
+	// followed by the message. A finding without a byte range is the
+	// decoder's own error, whose wording belongs to the decoder crate; it
+	// is written as "error decoder".
+	Findings []string
+}
+
+// ConformanceVectors returns the conformance suite: the standard fixtures
+// plus inputs for container rules the fixtures do not reach. Like the
+// fixtures, every vector is built from the embedded samples.
+func ConformanceVectors() []ConformanceVector {
+	return []ConformanceVector{
+		{FixtureStatic, "simple lossy file", ConformanceValid, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Decoded: 1,
+			Chunks: []string{"VP8 @12"},
+		}, fixtureData(FixtureStatic)},
+		{FixtureLossless, "simple lossless file; VP8L declares alpha in use", ConformanceValid, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Features: FeatureAlpha | FeatureLossless, Decoded: 1,
+			Chunks: []string{"VP8L@12"},
+		}, fixtureData(FixtureLossless)},
+		{FixtureAlpha, "extended lossy file with an ALPH chunk", ConformanceValid, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Features: FeatureAlpha, Decoded: 1,
+			Chunks: []string{"VP8X@12", "ALPH@30", "VP8 @50"},
+		}, fixtureData(FixtureAlpha)},
+		{FixtureAnimated, "three-frame animation of lossless frames", ConformanceValid, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, NumFrames: 3, Features: FeatureAnimation | FeatureLossless, Decoded: 3,
+			Chunks: []string{"VP8X@12", "ANIM@30", "ANMF@44", "VP8L@68", "ANMF@90", "VP8L@114", "ANMF@136", "VP8L@160"},
+		}, fixtureData(FixtureAnimated)},
+		{FixtureMetadata, "ICCP, EXIF and XMP chunks flagged in VP8X", ConformanceValid, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Features: FeatureICC | FeatureEXIF | FeatureXMP, Decoded: 1,
+			Chunks: []string{"VP8X@12", "ICCP@30", "VP8 @166", "EXIF@196", "XMP @216"},
+		}, fixtureData(FixtureMetadata)},
+		{"unknown-chunk.webp", "unknown chunk after the bitstream, which readers must skip", ConformanceValid, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Decoded: 1,
+			Chunks: []string{"VP8X@12", "VP8 @30", "ZZZZ@60"},
+		}, func() []byte {
+			return riffContainer(vp8xChunk(0), sampleBitstream(sampleLossy), riffChunk("ZZZZ", []byte("ok")))
+		}},
+		{FixtureTrailingData, "bytes after the RIFF container", ConformanceWarning, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Decoded: 1,
+			Chunks:   []string{"VP8 @12"},
+			Findings: []string{"warning@42+8 trailing data after riff container"},
+		}, fixtureData(FixtureTrailingData)},
+		{"missing-padding.webp", "odd-sized last chunk without its padding byte", ConformanceWarning, ConformanceExpectation{
+			Valid: true, Width: 1, Height: 1, Features: FeatureXMP, Decoded: 1,
+			Chunks:   []string{"VP8X@12", "VP8 @30", "XMP @60"},
+			Findings: []string{"warning@73+0 XMP  chunk is missing its padding byte"},
+		}, buildMissingPaddingVector},
+		{FixtureTruncated, "animation cut short inside its last frame", ConformanceInvalid, ConformanceExpectation{
+			Partial: true, Width: 1, Height: 1, NumFrames: 2, Features: FeatureAnimation | FeatureLossless,
+			Chunks: []string{"VP8X@12", "ANIM@30", "ANMF@44", "VP8L@68", "ANMF@90", "VP8L@114"},
+			Findings: []string{
+				"error@4+4 riff size declares 182 bytes, file has 172",
+				"error@136+36 ANMF chunk declares 38 bytes, only 28 available",
+			},
+		}, fixtureData(FixtureTruncated)},
+		{"riff-size-overflow.webp", "RIFF header declaring more bytes than the file holds", ConformanceInvalid, ConformanceExpectation{
+			Partial: true, Width: 1, Height: 1,
+			Chunks:   []string{"VP8 @12"},
+			Findings: []string{"error@4+4 riff size declares 142 bytes, file has 42"},
+		}, func() []byte {
+			data := bytes.Clone(sampleLossy)
+			binary.LittleEndian.PutUint32(data[4:], uint32(len(data)+92))
+			return data
+		}},
+		{FixtureChunkOverflow, "VP8 chunk declaring more bytes than the container holds", ConformanceInvalid, ConformanceExpectation{
+			Findings: []string{"error@12+30 VP8  chunk declares 4096 bytes, only 22 available"},
+		}, fixtureData(FixtureChunkOverflow)},
+		{"missing-bitstream.webp", "VP8X header with no image data", ConformanceInvalid, ConformanceExpectation{
+			Partial: true, Width: 1, Height: 1,
+			Chunks:   []string{"VP8X@12"},
+			Findings: []string{"error decoder"},
+		}, func() []byte { return riffContainer(vp8xChunk(0)) }},
+		{"short-header.webp", "file ending inside the RIFF header", ConformanceInvalid, ConformanceExpectation{
+			Findings: []string{"error@0+10 file too short for RIFF header"},
+		}, func() []byte { return []byte("RIFF\x04\x00\x00\x00WE") }},
+		{FixtureBadSignature, "RIFF container of form WAVE", ConformanceInvalid, ConformanceExpectation{
+			Findings: []string{"error@8+4 missing WEBP signature"},
+		}, fixtureData(FixtureBadSignature)},
+		{FixtureNotWebp, "PNG file", ConformanceInvalid, ConformanceExpectation{
+			Findings: []string{"error@0+4 missing RIFF signature"},
+		}, fixtureData(FixtureNotWebp)},
+		{FixtureEmpty, "zero bytes", ConformanceInvalid, ConformanceExpectation{
+			Findings: []string{"error@0+0 data is empty"},
+		}, fixtureData(FixtureEmpty)},
+	}
+}
+
+// fixtureData builds a vector from the named standard fixture.
+func fixtureData(name string) func() []byte {
+	return func() []byte {
+		fixture, ok := lookupFixture(name)
+		if !ok {
+			panic("unknown fixture " + name)
+		}
+		return fixture.Data()
+	}
+}
+
+func buildMissingPaddingVector() []byte {
+	data := riffContainer(vp8xChunk(0x04), sampleBitstream(sampleLossy), riffChunk("XMP ", []byte("<x/>x")))
+	data = data[:len(data)-1]
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	return data
+}
+
+var conformanceChecks = []string{"verdict", "info", "features", "chunks", "findings", "decode"}
+
+// ConformanceCheckNames returns the names of the checks every
+// ConformanceResult carries, in order.
+func ConformanceCheckNames() []string {
+	return slices.Clone(conformanceChecks)
+}
+
+// ConformanceCheck compares one aspect of a verdict with its expectation.
+// Want and Got are only set when the check fails.
+type ConformanceCheck struct {
+	Name string `json:"name"`
+	Pass bool   `json:"pass"`
+	Want string `json:"want,omitempty"`
+	Got  string `json:"got,omitempty"`
+}
+
+// ConformanceResult is the outcome of running one vector.
+type ConformanceResult struct {
+	Vector string             `json:"vector"`
+	Class  ConformanceClass   `json:"class"`
+	Pass   bool               `json:"pass"`
+	Checks []ConformanceCheck `json:"checks"`
+}
+
+// RunConformance validates, inspects and decodes v with the native
+// library and compares the results with its expectation. It bypasses
+// SetBackend, verdict caches and Stats, so the result certifies the
+// library itself rather than whatever answers for it.
+func RunConformance(v ConformanceVector) ConformanceResult {
+	data := v.Data()
+	info := NativeBackend.Validate(data)
+	got := buildVerdict(v.Name, data, info, NativeBackend.Inspect(data))
+	features := info.Features
+	want := v.Expect
+
+	result := ConformanceResult{Vector: v.Name, Class: v.Class, Pass: true}
+	add := func(name, wantText, gotText string) {
+		check := ConformanceCheck{Name: name, Pass: wantText == gotText}
+		if !check.Pass {
+			check.Want, check.Got = wantText, gotText
+			result.Pass = false
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	add("verdict", describeVerdict(want.Valid, want.Partial), describeVerdict(got.Valid, got.Partial))
+	add("info",
+		fmt.Sprintf("%dx%d, %d frames", want.Width, want.Height, want.NumFrames),
+		fmt.Sprintf("%dx%d, %d frames", got.Info.Width, got.Info.Height, got.Info.NumFrames))
+	add("features", want.Features.String(), features.String())
+
+	var chunks []string
+	for _, c := range got.Chunks {
+		chunks = append(chunks, fmt.Sprintf("%s@%d", c.FourCC, c.Offset))
+	}
+	add("chunks", describeList(want.Chunks), describeList(chunks))
+
+	var findings []string
+	for _, f := range got.Findings {
+		findings = append(findings, describeConformanceFinding(f))
+	}
+	add("findings", describeList(want.Findings), describeList(findings))
+
+	wantDecode := "error"
+	if want.Decoded > 0 {
+		wantDecode = fmt.Sprintf("%d frames of %dx%d", want.Decoded, want.Width, want.Height)
+	}
+	add("decode", wantDecode, describeConformanceDecode(data, info))
+	return result
+}
+
+// describeConformanceDecode decodes every frame of data. Invalid files are
+// decoded into a one-frame, one-pixel buffer for want of dimensions; the
+// decoder must refuse them before it looks at the buffers.
+func describeConformanceDecode(data []byte, info WebpInfo) string {
+	if !info.IsValid {
+		if err := NativeBackend.Decode(data, make([]byte, 4), make([]uint32, 1)); err != nil {
+			return "error"
+		}
+		return "decoded"
+	}
+	frames, err := decodeFrames(NativeBackend.Decode, data, info, frameCount(info))
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	bounds := frames[0].Image.Bounds()
+	return fmt.Sprintf("%d frames of %dx%d", len(frames), bounds.Dx(), bounds.Dy())
+}
+
+func describeVerdict(valid, partial bool) string {
+	switch {
+	case valid:
+		return "valid"
+	case partial:
+		return "invalid, partial"
+	default:
+		return "invalid"
+	}
+}
+
+func describeList(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, "; ")
+}
+
+func describeConformanceFinding(f report.Finding) string {
+	if f.Offset == nil || f.Length == nil {
+		return f.Severity + " decoder"
+	}
+	return fmt.Sprintf("%s@%d+%d %s", f.Severity, *f.Offset, *f.Length, f.Message)
+}
@@ -0,0 +1,94 @@
+package webpvalidator
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"webpValidatorTest/report"
+)
+
+func TestConformanceVectors(t *testing.T) {
+	names := map[string]bool{}
+	for _, v := range ConformanceVectors() {
+		assert.False(t, names[v.Name], "duplicate vector %s", v.Name)
+		names[v.Name] = true
+
+		result := RunConformance(v)
+		for _, check := range result.Checks {
+			assert.True(t, check.Pass, "%s %s: want %s, got %s", v.Name, check.Name, check.Want, check.Got)
+		}
+
+		// The class documents what the vector exercises; keep it honest.
+		r := Verdict(v.Name, v.Data())
+		class := ConformanceValid
+		switch {
+		case !r.Valid:
+			class = ConformanceInvalid
+		case slices.ContainsFunc(r.Findings, func(f report.Finding) bool { return f.Severity == report.SeverityWarning }):
+			class = ConformanceWarning
+		}
+		assert.Equal(t, v.Class, class, v.Name)
+	}
+
+	// Every standard fixture is part of the suite.
+	for _, fixture := range Fixtures() {
+		assert.True(t, names[fixture.Name], fixture.Name)
+	}
+}
+
+func TestConformanceDetectsMismatch(t *testing.T) {
+	v := ConformanceVectors()[0]
+	v.Expect.Width = 2
+	v.Expect.Findings = []string{"warning@0+0 imaginary"}
+
+	result := RunConformance(v)
+	assert.False(t, result.Pass)
+	var failed []string
+	for _, check := range result.Checks {
+		if !check.Pass {
+			failed = append(failed, check.Name)
+		}
+	}
+	assert.Equal(t, []string{"info", "findings", "decode"}, failed)
+	assert.Equal(t, "2x1, 0 frames", result.Checks[1].Want)
+	assert.Equal(t, "1x1, 0 frames", result.Checks[1].Got)
+	assert.Equal(t, "1 frames of 2x1", result.Checks[5].Want)
+	assert.Equal(t, "1 frames of 1x1", result.Checks[5].Got)
+
+	v = ConformanceVectors()[len(ConformanceVectors())-1]
+	v.Expect.Decoded = 1
+	decode := RunConformance(v).Checks[5]
+	assert.False(t, decode.Pass)
+	assert.Equal(t, "error", decode.Got, "an empty file must not decode")
+}
+
+func TestRunConformanceBypassesBackend(t *testing.T) {
+	// Certification is about the library, not whatever SetBackend put in
+	// front of it.
+	previous := SetBackend(everythingValidBackend{})
+	defer SetBackend(previous)
+
+	before := Stats()
+	for _, v := range ConformanceVectors() {
+		assert.True(t, RunConformance(v).Pass, v.Name)
+	}
+	assert.Equal(t, before, Stats(), "conformance does not go through ValidateWebp")
+}
+
+type everythingValidBackend struct{}
+
+func (everythingValidBackend) Validate(data []byte) WebpInfo {
+	return WebpInfo{IsValid: true, Width: 1, Height: 1}
+}
+
+func (everythingValidBackend) Inspect(data []byte) WebpInspection { return NativeBackend.Inspect(data) }
+func (everythingValidBackend) SupportedFeatures() (Features, error) {
+	return NativeBackend.SupportedFeatures()
+}
+func (everythingValidBackend) Version() (string, error) { return NativeBackend.Version() }
+
+func (everythingValidBackend) Decode(data, pixels []byte, durations []uint32) error {
+	return NativeBackend.Decode(data, pixels, durations)
+}
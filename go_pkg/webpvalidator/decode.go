@@ -0,0 +1,229 @@
+package webpvalidator
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxDecodedBytes caps the RGBA pixel memory a Decoded may allocate: every
+// frame of an animation is kept, so a long animation costs frames times
+// width times height times 4 bytes.
+const MaxDecodedBytes int64 = 1 << 30
+
+// DecodedFrame is one fully composited frame.
+type DecodedFrame struct {
+	Image    *image.RGBA
+	Duration time.Duration
+}
+
+// Decoded is a request-scoped handle on one validated image. Pixels are
+// decoded at most once, on first use, and shared by every derived result
+// (thumbnails, placeholder hash, dominant color), which are cached too.
+// Results derived from the first frame only decode that frame unless every
+// frame has already been decoded.
+// Pipelines that run several checks on one file should open it once and
+// pass the handle along instead of the bytes.
+//
+// A Decoded is safe for concurrent use; the images it returns are shared
+// and must not be modified.
+type Decoded struct {
+	data []byte
+	info WebpInfo
+
+	decodeOnce sync.Once
+	decoded    atomic.Bool
+	frames     []DecodedFrame
+	decodeErr  error
+
+	firstOnce sync.Once
+	first     *image.RGBA
+	firstErr  error
+
+	mu          sync.Mutex
+	thumbnails  map[int]*image.RGBA
+	placeholder string
+	dominant    *color.RGBA
+}
+
+// Open reads and validates the file at path. It fails if the file cannot
+// be read, is not a valid WebP image, or changes while being validated
+// (ErrFileChangedDuringValidation).
+func Open(path string) (*Decoded, error) {
+	data, info := validateFile(path)
+	if info.Err != nil {
+		return nil, info.Err
+	}
+	if !info.IsValid {
+		return nil, errors.New(info.Error)
+	}
+	return &Decoded{data: data, info: info}, nil
+}
+
+// OpenBytes validates data and returns a handle on it. data must not be
+// modified while the handle is in use.
+func OpenBytes(data []byte) (*Decoded, error) {
+	info := ValidateWebp(data)
+	if !info.IsValid {
+		return nil, errors.New(info.Error)
+	}
+	return &Decoded{data: data, info: info}, nil
+}
+
+// Info returns the validation result.
+func (d *Decoded) Info() WebpInfo {
+	return d.info
+}
+
+// Bytes returns the encoded file.
+func (d *Decoded) Bytes() []byte {
+	return d.data
+}
+
+// Frames returns every frame as RGBA, decoding on the first call. Still
+// images have a single frame with zero duration.
+func (d *Decoded) Frames() ([]DecodedFrame, error) {
+	hit := true
+	d.decodeOnce.Do(func() {
+		hit = false
+		counters.decodes.Add(1)
+		d.frames, d.decodeErr = decodeFrames(decodeWebp, d.data, d.info, frameCount(d.info))
+		d.decoded.Store(true)
+	})
+	recordCacheLookup(hit)
+	return d.frames, d.decodeErr
+}
+
+// Image returns the first frame. For an animation whose frames have not
+// been decoded yet, only the first frame is decoded.
+func (d *Decoded) Image() (*image.RGBA, error) {
+	if !d.info.IsAnimated || d.decoded.Load() {
+		frames, err := d.Frames()
+		if err != nil {
+			return nil, err
+		}
+		return frames[0].Image, nil
+	}
+
+	hit := true
+	d.firstOnce.Do(func() {
+		hit = false
+		counters.decodes.Add(1)
+		frames, err := decodeFrames(decodeWebp, d.data, d.info, 1)
+		if err != nil {
+			d.firstErr = err
+			return
+		}
+		d.first = frames[0].Image
+	})
+	recordCacheLookup(hit)
+	return d.first, d.firstErr
+}
+
+// Thumbnail returns the first frame scaled down so its longer side is at
+// most maxSize pixels, preserving the aspect ratio. Images that already
+// fit are returned unscaled.
+func (d *Decoded) Thumbnail(maxSize int) (*image.RGBA, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid thumbnail size: %d", maxSize)
+	}
+	img, err := d.Image()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	thumb, ok := d.thumbnails[maxSize]
+	recordCacheLookup(ok)
+	if ok {
+		return thumb, nil
+	}
+	if d.thumbnails == nil {
+		d.thumbnails = make(map[int]*image.RGBA)
+	}
+	thumb = scaleDown(img, maxSize)
+	d.thumbnails[maxSize] = thumb
+	return thumb, nil
+}
+
+// PlaceholderHash returns a BlurHash of the first frame with 4x3
+// components, suitable for rendering a blurred placeholder before the
+// image loads. Transparency is ignored.
+func (d *Decoded) PlaceholderHash() (string, error) {
+	thumb, err := d.Thumbnail(placeholderSampleSize)
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	recordCacheLookup(d.placeholder != "")
+	if d.placeholder == "" {
+		d.placeholder = blurHash(thumb, 4, 3)
+	}
+	return d.placeholder, nil
+}
+
+// DominantColor returns the most common color of the first frame's
+// mostly-opaque pixels, quantized to 16 levels per channel and averaged
+// within that bucket. Fully transparent images return a zero color.
+func (d *Decoded) DominantColor() (color.RGBA, error) {
+	thumb, err := d.Thumbnail(dominantSampleSize)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	recordCacheLookup(d.dominant != nil)
+	if d.dominant == nil {
+		c := dominantColor(thumb)
+		d.dominant = &c
+	}
+	return *d.dominant, nil
+}
+
+// frameCount returns how many frames decoding info's image yields.
+func frameCount(info WebpInfo) int {
+	if info.IsAnimated {
+		return int(info.NumFrames)
+	}
+	return 1
+}
+
+// decodeFrames allocates one buffer for the first count frames and has
+// decode fill it.
+func decodeFrames(decode func(data, pixels []byte, durations []uint32) error, data []byte, info WebpInfo, count int) ([]DecodedFrame, error) {
+	frameLen := int64(info.Width) * int64(info.Height) * 4
+	if count == 0 || frameLen == 0 {
+		return nil, errors.New("webp image has no frames to decode")
+	}
+	if total := frameLen * int64(count); total > MaxDecodedBytes {
+		return nil, fmt.Errorf("decoded image too large: %d bytes (max %d)", total, MaxDecodedBytes)
+	}
+
+	pixels := make([]byte, frameLen*int64(count))
+	durations := make([]uint32, count)
+	if err := decode(data, pixels, durations); err != nil {
+		return nil, err
+	}
+
+	frames := make([]DecodedFrame, count)
+	bounds := image.Rect(0, 0, int(info.Width), int(info.Height))
+	for i := range frames {
+		frames[i] = DecodedFrame{
+			Image: &image.RGBA{
+				Pix:    pixels[int64(i)*frameLen : int64(i+1)*frameLen : int64(i+1)*frameLen],
+				Stride: int(info.Width) * 4,
+				Rect:   bounds,
+			},
+			Duration: time.Duration(durations[i]) * time.Millisecond,
+		}
+	}
+	return frames, nil
+}
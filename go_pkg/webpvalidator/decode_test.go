@@ -0,0 +1,150 @@
+package webpvalidator
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenStatic(t *testing.T) {
+	decoded, err := Open(mustFixturePath(t, FixtureStatic))
+	require.NoError(t, err)
+	info := decoded.Info()
+	require.True(t, info.IsValid)
+
+	frames, err := decoded.Frames()
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+	assert.Equal(t, image.Rect(0, 0, int(info.Width), int(info.Height)), frames[0].Image.Bounds())
+	assert.Zero(t, frames[0].Duration)
+
+	again, err := decoded.Frames()
+	require.NoError(t, err)
+	assert.Same(t, frames[0].Image, again[0].Image, "frames should be decoded once")
+
+	thumb, err := decoded.Thumbnail(16)
+	require.NoError(t, err)
+	assert.Equal(t, frames[0].Image.Bounds(), thumb.Bounds(), "images that fit are not enlarged")
+	cached, err := decoded.Thumbnail(16)
+	require.NoError(t, err)
+	assert.Same(t, thumb, cached)
+
+	hash, err := decoded.PlaceholderHash()
+	require.NoError(t, err)
+	require.Len(t, hash, 28)
+	assert.Equal(t, byte('L'), hash[0], "4x3 components")
+
+	dominant, err := decoded.DominantColor()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0xff), dominant.A)
+}
+
+func TestOpenAnimated(t *testing.T) {
+	decoded, err := Open(mustFixturePath(t, FixtureAnimated))
+	require.NoError(t, err)
+
+	// Every step of a pipeline shares the one decode.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := decoded.PlaceholderHash()
+			assert.NoError(t, err)
+			_, err = decoded.DominantColor()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	frames, err := decoded.Frames()
+	require.NoError(t, err)
+	require.Len(t, frames, int(decoded.Info().NumFrames))
+	require.NotEmpty(t, frames)
+	assert.Positive(t, frames[0].Duration)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	_, err := Open(mustFixturePath(t, FixtureNotWebp))
+	assert.ErrorContains(t, err, "webp format validation failed")
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.webp"))
+	assert.ErrorContains(t, err, "failed to read file")
+}
+
+func TestImageDecodesFirstFrame(t *testing.T) {
+	decoded, err := OpenBytes(sampleAnimated)
+	require.NoError(t, err)
+	_, err = decoded.PlaceholderHash()
+	require.NoError(t, err)
+	assert.False(t, decoded.decoded.Load(), "placeholder should not decode every frame")
+
+	img, err := decoded.Image()
+	require.NoError(t, err)
+	frames, err := decoded.Frames()
+	require.NoError(t, err)
+	require.Len(t, frames, int(decoded.Info().NumFrames))
+	assert.Equal(t, frames[0].Image.Pix, img.Pix)
+
+	// Once every frame is decoded, Image shares the full decode.
+	again, err := decoded.Image()
+	require.NoError(t, err)
+	assert.Same(t, frames[0].Image, again)
+}
+
+func TestDecodedSamples(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"lossy":    sampleLossy,
+		"lossless": sampleLossless,
+		"alpha":    sampleAlpha,
+		"animated": sampleAnimated,
+	} {
+		decoded, err := OpenBytes(data)
+		require.NoError(t, err, name)
+		img, err := decoded.Image()
+		require.NoError(t, err, name)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), img.Bounds(), name)
+	}
+}
+
+func TestBlurHashUniform(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []byte{0xff, 0, 0, 0xff})
+	}
+
+	// Size flag 'L' is 4x3 components; "TI:j" is the DC term for pure red.
+	hash := blurHash(img, 4, 3)
+	assert.Len(t, hash, 28)
+	assert.Equal(t, "L", hash[:1])
+	assert.Equal(t, "TI:j", hash[2:6])
+	assert.Equal(t, color.RGBA{0xff, 0, 0, 0xff}, dominantColor(img))
+}
+
+func TestScaleDown(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := 0; i < len(img.Pix); i += 4 {
+		v := byte(0)
+		if (i/4)%2 == 1 {
+			v = 200
+		}
+		copy(img.Pix[i:], []byte{v, v, v, 0xff})
+	}
+
+	thumb := scaleDown(img, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), thumb.Bounds())
+	assert.Equal(t, []byte{100, 100, 100, 0xff, 100, 100, 100, 0xff}, thumb.Pix)
+	assert.Same(t, img, scaleDown(img, 8))
+
+	_, err := (&Decoded{}).Thumbnail(0)
+	assert.Error(t, err)
+}
+
+func TestDominantColorTransparent(t *testing.T) {
+	assert.Equal(t, color.RGBA{}, dominantColor(image.NewRGBA(image.Rect(0, 0, 2, 2))))
+}
@@ -0,0 +1,14 @@
+//go:build linux && webp_embed
+
+package webpvalidator
+
+import _ "embed"
+
+// Copy the built library to embedded/ before building with -tags webp_embed.
+//
+//go:embed embedded/libwebp_validator.so
+var embeddedLibraryData []byte
+
+func init() {
+	embeddedLibrary = embeddedLibraryData
+}
@@ -0,0 +1,14 @@
+//go:build windows && webp_embed
+
+package webpvalidator
+
+import _ "embed"
+
+// Copy the built library to embedded/ before building with -tags webp_embed.
+//
+//go:embed embedded/webp_validator.dll
+var embeddedLibraryData []byte
+
+func init() {
+	embeddedLibrary = embeddedLibraryData
+}
@@ -0,0 +1,27 @@
+//go:build linux && (amd64 || arm64)
+
+package webpvalidator
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	fadvSequential = 2 // POSIX_FADV_SEQUENTIAL
+	fadvWillNeed   = 3 // POSIX_FADV_WILLNEED
+)
+
+// adviseSequential tells the kernel f is about to be read once from start
+// to end, doubling its readahead window.
+func adviseSequential(f *os.File) { fadvise(f, fadvSequential) }
+
+// adviseWillNeed starts reading all of f into the page cache in the
+// background.
+func adviseWillNeed(f *os.File) { fadvise(f, fadvWillNeed) }
+
+// fadvise applies advice to the whole file. Advice is only a hint, so
+// errors are ignored.
+func fadvise(f *os.File, advice uintptr) {
+	syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, advice, 0, 0)
+}
@@ -0,0 +1,12 @@
+//go:build !linux || !(amd64 || arm64)
+
+package webpvalidator
+
+import "os"
+
+// Windows has no per-file advice once a file is open, and 32-bit Linux
+// passes fadvise offsets split across registers; both go without hints.
+
+func adviseSequential(*os.File) {}
+
+func adviseWillNeed(*os.File) {}
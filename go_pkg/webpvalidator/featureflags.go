@@ -0,0 +1,60 @@
+package webpvalidator
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Features is a set of capabilities a webp file uses, carried bit for bit
+// from the native library (WEBP_FEATURE_* in webp_validator.h). New bits
+// may appear with newer libraries without any change to WebpInfo: they
+// survive in the value, Has works for them, and Unknown and String expose
+// them raw.
+type Features uint32
+
+const (
+	FeatureAlpha Features = 1 << iota
+	FeatureAnimation
+	FeatureICC
+	FeatureEXIF
+	FeatureXMP
+	// FeatureLossless marks at least one VP8L (lossless) bitstream.
+	FeatureLossless
+	// FeatureFragments marks the obsolete fragmented-image extension.
+	FeatureFragments
+
+	// KnownFeatures is every bit these bindings have a name for.
+	KnownFeatures = FeatureAlpha | FeatureAnimation | FeatureICC | FeatureEXIF |
+		FeatureXMP | FeatureLossless | FeatureFragments
+)
+
+var featureNames = []string{"alpha", "animation", "icc", "exif", "xmp", "lossless", "fragments"}
+
+// Has reports whether every bit of want is set.
+func (f Features) Has(want Features) bool {
+	return f&want == want
+}
+
+// Unknown returns the bits these bindings have no name for.
+func (f Features) Unknown() Features {
+	return f &^ KnownFeatures
+}
+
+// String lists the set features separated by "|", e.g. "alpha|icc",
+// with unknown bits as hex, e.g. "alpha|0x80". The empty set is "none".
+func (f Features) String() string {
+	if f == 0 {
+		return "none"
+	}
+	var names []string
+	for rest := f; rest != 0; rest &= rest - 1 {
+		bit := bits.TrailingZeros32(uint32(rest))
+		if bit < len(featureNames) {
+			names = append(names, featureNames[bit])
+		} else {
+			names = append(names, fmt.Sprintf("%#x", uint32(1)<<bit))
+		}
+	}
+	return strings.Join(names, "|")
+}
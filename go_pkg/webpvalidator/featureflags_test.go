@@ -0,0 +1,58 @@
+package webpvalidator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeaturesString(t *testing.T) {
+	assert.Equal(t, "none", Features(0).String())
+	assert.Equal(t, "alpha|icc|lossless", (FeatureAlpha | FeatureICC | FeatureLossless).String())
+	assert.Equal(t, "animation|0x80|0x80000000", (FeatureAnimation | 1<<7 | 1<<31).String())
+
+	f := FeatureEXIF | 1<<9
+	assert.True(t, f.Has(FeatureEXIF))
+	assert.True(t, f.Has(1<<9), "unknown bits can be tested too")
+	assert.False(t, f.Has(FeatureEXIF|FeatureXMP))
+	assert.Equal(t, Features(1<<9), f.Unknown())
+	assert.Zero(t, KnownFeatures.Unknown())
+}
+
+func TestFeaturesFromNative(t *testing.T) {
+	supported, err := SupportedFeatures()
+	require.NoError(t, err)
+	assert.True(t, supported.Has(KnownFeatures))
+
+	for _, tc := range []struct {
+		name string
+		want Features
+	}{
+		{FixtureStatic, 0},
+		{FixtureLossless, FeatureAlpha | FeatureLossless}, // VP8L sets alpha_is_used
+		{FixtureAlpha, FeatureAlpha},
+		{FixtureAnimated, FeatureAnimation | FeatureLossless},
+		{FixtureMetadata, FeatureICC | FeatureEXIF | FeatureXMP},
+		// Recovered from the container for files that fail validation.
+		{FixtureTruncated, FeatureAnimation | FeatureLossless},
+	} {
+		fixture, ok := lookupFixture(tc.name)
+		require.True(t, ok, tc.name)
+		info := ValidateWebp(fixture.Data())
+		assert.Equal(t, tc.want, info.Features, "%s: %s", tc.name, info.Features)
+		assert.Equal(t, info.HasAlpha, info.Features.Has(FeatureAlpha), tc.name)
+		assert.Equal(t, info.IsAnimated, info.Features.Has(FeatureAnimation), tc.name)
+	}
+}
+
+func TestFeaturesKeepUnknownBits(t *testing.T) {
+	// A newer library's bits survive a record/replay round trip.
+	info := WebpInfo{IsValid: true, Features: FeatureAlpha | 1<<20}
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	var decoded WebpInfo
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, info.Features, decoded.Features)
+}
@@ -0,0 +1,246 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FixturesEnv names the environment variable that overrides the fixture
+// directory.
+const FixturesEnv = "WEBP_VALIDATOR_FIXTURES"
+
+// fixtureSetVersion names the cache subdirectory; bump it whenever a
+// fixture's bytes change so stale caches are never reused.
+const fixtureSetVersion = "v1"
+
+// Names of the standard fixtures, for FixturePath.
+const (
+	FixtureStatic        = "static.webp"
+	FixtureLossless      = "lossless.webp"
+	FixtureAlpha         = "alpha.webp"
+	FixtureAnimated      = "animated.webp"
+	FixtureMetadata      = "metadata.webp"
+	FixtureTrailingData  = "trailing-data.webp"
+	FixtureTruncated     = "truncated.webp"
+	FixtureChunkOverflow = "chunk-overflow.webp"
+	FixtureBadSignature  = "bad-signature.webp"
+	FixtureNotWebp       = "not-webp.webp"
+	FixtureEmpty         = "empty.webp"
+)
+
+// Fixture describes one file of the standard fixture set and the result
+// validating it must produce.
+type Fixture struct {
+	Name        string
+	Description string
+	Valid       bool
+	Animated    bool
+	// Frames is the expected NumFrames for animated fixtures.
+	Frames uint32
+	build  func() []byte
+}
+
+// Data returns the fixture's contents, a fresh copy on every call that
+// the caller may modify.
+func (f Fixture) Data() []byte {
+	return f.build()
+}
+
+// Fixtures returns the standard fixture set. Every file is built from the
+// embedded samples, so creating the set needs neither network access nor
+// the native library.
+func Fixtures() []Fixture {
+	return []Fixture{
+		{FixtureStatic, "1x1 lossy (VP8)", true, false, 0, func() []byte { return bytes.Clone(sampleLossy) }},
+		{FixtureLossless, "1x1 lossless (VP8L)", true, false, 0, func() []byte { return bytes.Clone(sampleLossless) }},
+		{FixtureAlpha, "1x1 lossy with ALPH (VP8X)", true, false, 0, func() []byte { return bytes.Clone(sampleAlpha) }},
+		{FixtureAnimated, "1x1 animation, 3 frames of 100, 200 and 300ms", true, true, 3, buildAnimatedFixture},
+		{FixtureMetadata, "1x1 lossy with ICCP, EXIF and XMP chunks", true, false, 0, buildMetadataFixture},
+		{FixtureTrailingData, "valid image followed by bytes outside the RIFF container", true, false, 0, func() []byte {
+			return append(bytes.Clone(sampleLossy), "trailing"...)
+		}},
+		{FixtureTruncated, "animation cut short inside its last frame", false, true, 0, func() []byte {
+			data := buildAnimatedFixture()
+			return data[:len(data)-10]
+		}},
+		{FixtureChunkOverflow, "VP8 chunk declaring more bytes than the container holds", false, false, 0, func() []byte {
+			data := bytes.Clone(sampleLossy)
+			binary.LittleEndian.PutUint32(data[16:], 0x1000)
+			return data
+		}},
+		{FixtureBadSignature, "RIFF container of form WAVE instead of WEBP", false, false, 0, func() []byte {
+			data := bytes.Clone(sampleLossy)
+			copy(data[8:], "WAVE")
+			return data
+		}},
+		{FixtureNotWebp, "PNG signature with a .webp name", false, false, 0, func() []byte {
+			return []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+		}},
+		{FixtureEmpty, "zero bytes", false, false, 0, func() []byte { return nil }},
+	}
+}
+
+func lookupFixture(name string) (Fixture, bool) {
+	for _, fixture := range Fixtures() {
+		if fixture.Name == name {
+			return fixture, true
+		}
+	}
+	return Fixture{}, false
+}
+
+// riffChunk encodes one chunk, including its padding byte.
+func riffChunk(fourcc string, payload []byte) []byte {
+	chunk := make([]byte, 8, 8+len(payload)+1)
+	copy(chunk, fourcc)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// riffContainer wraps chunks in a RIFF/WEBP header.
+func riffContainer(chunks ...[]byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	return data
+}
+
+// vp8xChunk describes a 1x1 canvas with the given feature flags.
+func vp8xChunk(flags byte) []byte {
+	return riffChunk("VP8X", []byte{flags, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+}
+
+// sampleBitstream returns the first VP8 or VP8L chunk of sample, whole.
+func sampleBitstream(sample []byte) []byte {
+	for offset := 12; offset+8 <= len(sample); {
+		size := int(binary.LittleEndian.Uint32(sample[offset+4:]))
+		end := offset + 8 + size + size%2
+		if fourcc := string(sample[offset : offset+4]); fourcc == "VP8 " || fourcc == "VP8L" {
+			return sample[offset:min(end, len(sample))]
+		}
+		offset = end
+	}
+	panic("sample has no bitstream chunk")
+}
+
+func buildAnimatedFixture() []byte {
+	chunks := [][]byte{
+		vp8xChunk(0x02),
+		// Background color, then loop count 0 (forever).
+		riffChunk("ANIM", []byte{0xff, 0xff, 0xff, 0xff, 0, 0}),
+	}
+	bitstream := sampleBitstream(sampleLossless)
+	for _, duration := range []uint32{100, 200, 300} {
+		// X/2, Y/2, width-1 and height-1 (all 0), a 24-bit duration, flags.
+		header := make([]byte, 16)
+		header[12], header[13], header[14] = byte(duration), byte(duration>>8), byte(duration>>16)
+		chunks = append(chunks, riffChunk("ANMF", append(header, bitstream...)))
+	}
+	return riffContainer(chunks...)
+}
+
+func buildMetadataFixture() []byte {
+	exif := []byte("II*\x00\x08\x00\x00\x00\x00\x00\x00\x00")
+	xmp := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"></x:xmpmeta>`)
+	// A header-only ICC profile: the validator carries ICCP, it does not
+	// apply it.
+	icc := make([]byte, 128)
+	binary.BigEndian.PutUint32(icc, 128)
+	copy(icc[36:], "acsp")
+	return riffContainer(
+		vp8xChunk(0x20|0x08|0x04),
+		riffChunk("ICCP", icc),
+		sampleBitstream(sampleLossy),
+		riffChunk("EXIF", exif),
+		riffChunk("XMP ", xmp),
+	)
+}
+
+// FixtureDir returns the directory FixturePath uses: $WEBP_VALIDATOR_FIXTURES
+// if set, otherwise a versioned directory in the user cache.
+func FixtureDir() (string, error) {
+	if dir := os.Getenv(FixturesEnv); dir != "" {
+		return dir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(cache, "webp-validator", "fixtures", fixtureSetVersion), nil
+}
+
+// FetchFixtures writes the standard fixture set to dir, creating it if
+// needed. Files that already hold the expected bytes are left alone, so
+// repeated calls are cheap and do not disturb concurrent readers.
+func FetchFixtures(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	for _, fixture := range Fixtures() {
+		path := filepath.Join(dir, fixture.Name)
+		data := fixture.Data()
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+
+		tmp, err := os.CreateTemp(dir, fixture.Name+".*")
+		if err != nil {
+			return fmt.Errorf("failed to write fixture: %w", err)
+		}
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("failed to write fixture %s: %w", fixture.Name, err)
+		}
+	}
+	return nil
+}
+
+var fetchOnce struct {
+	sync.Mutex
+	done map[string]bool
+}
+
+// FixturePath returns the path of the named standard fixture, fetching
+// the set into FixtureDir on first use:
+//
+//	path, err := FixturePath(FixtureAnimated)
+func FixturePath(name string) (string, error) {
+	if _, ok := lookupFixture(name); !ok {
+		return "", fmt.Errorf("unknown fixture %q", name)
+	}
+
+	dir, err := FixtureDir()
+	if err != nil {
+		return "", err
+	}
+
+	fetchOnce.Lock()
+	defer fetchOnce.Unlock()
+	if !fetchOnce.done[dir] {
+		if err := FetchFixtures(dir); err != nil {
+			return "", err
+		}
+		if fetchOnce.done == nil {
+			fetchOnce.done = make(map[string]bool)
+		}
+		fetchOnce.done[dir] = true
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,87 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixturesValidate(t *testing.T) {
+	for _, fixture := range Fixtures() {
+		info := ValidateWebp(fixture.Data())
+		assert.Equal(t, fixture.Valid, info.IsValid, "%s: %s", fixture.Name, info.Error)
+		if fixture.Valid {
+			assert.Equal(t, fixture.Animated, info.IsAnimated, fixture.Name)
+			assert.Equal(t, fixture.Frames, info.NumFrames, fixture.Name)
+		}
+	}
+}
+
+func TestFixtureDataIsACopy(t *testing.T) {
+	for _, fixture := range Fixtures() {
+		data := fixture.Data()
+		if len(data) == 0 {
+			continue
+		}
+		want := bytes.Clone(data)
+		data[0] ^= 0xff
+		assert.Equal(t, want, fixture.Data(), fixture.Name)
+	}
+	assert.True(t, ValidateWebp(sampleLossy).IsValid, "the embedded samples are untouched")
+}
+
+func TestFixtureInspection(t *testing.T) {
+	metadata, ok := lookupFixture(FixtureMetadata)
+	require.True(t, ok)
+	inspection := InspectWebp(metadata.Data())
+	var fourccs []string
+	for _, c := range inspection.Chunks {
+		fourccs = append(fourccs, c.FourCC)
+	}
+	assert.Equal(t, []string{"VP8X", "ICCP", "VP8 ", "EXIF", "XMP "}, fourccs)
+	assert.Empty(t, inspection.Findings)
+
+	data, err := os.ReadFile(mustFixturePath(t, FixtureTrailingData))
+	require.NoError(t, err)
+	findings := InspectWebp(data).Findings
+	require.Len(t, findings, 1)
+	assert.True(t, findings[0].Warning)
+}
+
+func TestFixturePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(FixturesEnv, dir)
+
+	path := mustFixturePath(t, FixtureAnimated)
+	assert.Equal(t, filepath.Join(dir, FixtureAnimated), path)
+	info := ValidateWebpFile(path)
+	assert.True(t, info.IsValid, info.Error)
+	assert.Equal(t, uint32(3), info.NumFrames)
+
+	_, err := FixturePath("nope.webp")
+	assert.ErrorContains(t, err, "unknown fixture")
+}
+
+// mustFixturePath returns the path of the named fixture, fetched into a
+// temporary directory unless $WEBP_VALIDATOR_FIXTURES is already set.
+func mustFixturePath(t testing.TB, name string) string {
+	t.Helper()
+	if os.Getenv(FixturesEnv) == "" {
+		t.Setenv(FixturesEnv, t.TempDir())
+	}
+	path, err := FixturePath(name)
+	require.NoError(t, err)
+	return path
+}
+
+// mustFixtureData returns the contents of the named fixture.
+func mustFixtureData(t testing.TB, name string) []byte {
+	t.Helper()
+	fixture, ok := lookupFixture(name)
+	require.True(t, ok, "no fixture %s", name)
+	return fixture.Data()
+}
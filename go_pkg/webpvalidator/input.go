@@ -0,0 +1,156 @@
+package webpvalidator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// MaxWebpFileSize is the largest file a RIFF container can describe: the
+// 8-byte RIFF header followed by a payload whose size is stored as a u32.
+const MaxWebpFileSize int64 = 8 + math.MaxUint32
+
+// ErrFileChangedDuringValidation reports that a file was written, resized
+// or replaced while it was being read or validated, so the result would
+// describe neither the old nor the new contents. Retrying once the writer
+// is done is usually enough.
+var ErrFileChangedDuringValidation = errors.New("file changed during validation")
+
+// ValidateWebpFile reads the file at path and validates its contents.
+// Files larger than MaxWebpFileSize are rejected without being read.
+// If the file changes before validation finishes, the result carries
+// ErrFileChangedDuringValidation in Err instead of a misleading
+// corruption error. Either way it counts as one validation in Stats.
+func ValidateWebpFile(path string) WebpInfo {
+	_, info := validateFile(path)
+	return info
+}
+
+// validateFile reads and validates the file at path, checks it did not
+// change, and counts the final result as one validation.
+func validateFile(path string) ([]byte, WebpInfo) {
+	data, snapshot, err := ReadWebpFile(path, nil)
+	if err != nil {
+		info := failedInfo(err)
+		recordValidation(info, 0)
+		return nil, info
+	}
+
+	info := validate(data)
+	if err := snapshot.Verify(); err != nil {
+		info = failedInfo(err)
+	}
+	recordValidation(info, len(data))
+	return data, info
+}
+
+// failedInfo is the result of a validation that failed with err before or
+// after the bytes reached the validator.
+func failedInfo(err error) WebpInfo {
+	return WebpInfo{
+		IsValid: false,
+		Error:   err.Error(),
+		Err:     err,
+	}
+}
+
+// FileSnapshot identifies the version of a file that was read, so callers
+// that validate the bytes themselves can check afterwards that the file
+// did not change underneath them.
+type FileSnapshot struct {
+	path string
+	stat os.FileInfo
+}
+
+// Verify returns ErrFileChangedDuringValidation if the file at the
+// snapshot's path is no longer the one that was read. It does not count
+// in Stats; the validation of the bytes already has.
+func (s FileSnapshot) Verify() error {
+	stat, err := os.Stat(s.path)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: %v", ErrFileChangedDuringValidation, err)
+	case !os.SameFile(s.stat, stat):
+		return fmt.Errorf("%w: file was replaced", ErrFileChangedDuringValidation)
+	case stat.Size() != s.stat.Size():
+		return fmt.Errorf("%w: size changed from %d to %d bytes", ErrFileChangedDuringValidation, s.stat.Size(), stat.Size())
+	case !stat.ModTime().Equal(s.stat.ModTime()):
+		return fmt.Errorf("%w: modified at %s", ErrFileChangedDuringValidation, stat.ModTime().Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// ReadWebpFile reads exactly the size the file had when opened, with reads
+// counted against throttle (nil for none, which also allows a
+// sequential-access hint to the kernel), and returns a snapshot for
+// checking after validation that the file did not change. Files larger
+// than MaxWebpFileSize are rejected without being read, and a file that
+// shrinks or grows while being read is reported as changed. Reading is not
+// validating, so ReadWebpFile does not count in Stats.
+func ReadWebpFile(path string, throttle *Throttle) ([]byte, FileSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, FileSnapshot{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, FileSnapshot{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := checkWebpFileSize(stat.Size()); err != nil {
+		return nil, FileSnapshot{}, err
+	}
+	if throttle == nil {
+		// A larger readahead window would read past what the throttle
+		// has allowed.
+		readAhead.sequential(f)
+	}
+
+	data := make([]byte, stat.Size())
+	n, err := io.ReadFull(throttle.Reader(f), data)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return nil, FileSnapshot{}, fmt.Errorf("%w: short read, got %d of %d bytes", ErrFileChangedDuringValidation, n, len(data))
+	}
+	if err != nil {
+		return nil, FileSnapshot{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if n, _ := f.Read(make([]byte, 1)); n > 0 {
+		return nil, FileSnapshot{}, fmt.Errorf("%w: file grew past %d bytes while reading", ErrFileChangedDuringValidation, len(data))
+	}
+
+	return data, FileSnapshot{path: path, stat: stat}, nil
+}
+
+// checkWebpFileSize rejects file sizes that cannot be a single RIFF
+// container or cannot be held in memory on this platform.
+func checkWebpFileSize(size int64) error {
+	if size > MaxWebpFileSize {
+		return fmt.Errorf("webp file exceeds riff size limit: %d bytes (max %d)", size, MaxWebpFileSize)
+	}
+	if size > math.MaxInt {
+		return fmt.Errorf("webp file too large for this platform: %d bytes", size)
+	}
+	return nil
+}
+
+// ValidateWebpReader reads r until EOF and validates the contents.
+// Reading stops once more than MaxWebpFileSize bytes have been seen.
+func ValidateWebpReader(r io.Reader) WebpInfo {
+	data, err := io.ReadAll(io.LimitReader(r, MaxWebpFileSize+1))
+	if err != nil {
+		err = fmt.Errorf("failed to read data: %w", err)
+	} else if int64(len(data)) > MaxWebpFileSize {
+		err = fmt.Errorf("webp file exceeds riff size limit: more than %d bytes", MaxWebpFileSize)
+	}
+	if err != nil {
+		info := failedInfo(err)
+		recordValidation(info, len(data))
+		return info
+	}
+
+	return ValidateWebp(data)
+}
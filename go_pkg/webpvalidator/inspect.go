@@ -0,0 +1,86 @@
+package webpvalidator
+
+// WebpChunk locates a chunk in the RIFF container.
+type WebpChunk struct {
+	FourCC string
+	// Offset of the chunk header from the start of the file.
+	Offset uint64
+	// Size is the payload size declared in the chunk header, excluding
+	// the padding byte.
+	Size uint32
+	// Depth is 0 for top-level chunks and 1 for chunks inside an ANMF frame.
+	Depth uint32
+}
+
+// PayloadOffset returns the offset of the first payload byte.
+func (c WebpChunk) PayloadOffset() uint64 {
+	return c.Offset + 8
+}
+
+// Length returns the number of bytes the chunk occupies, including its
+// header and padding byte.
+func (c WebpChunk) Length() uint64 {
+	return 8 + uint64(c.Size) + uint64(c.Size&1)
+}
+
+// WebpFinding is a structural problem located at a byte range.
+type WebpFinding struct {
+	Offset  uint64
+	Length  uint64
+	Warning bool
+	Message string
+}
+
+// WebpInspection is the container structure of a WebP file.
+type WebpInspection struct {
+	// Chunks lists every complete chunk in file order; chunks nested in an
+	// ANMF frame follow the frame.
+	Chunks   []WebpChunk
+	Findings []WebpFinding
+}
+
+// WebpFrame is the header of an ANMF animation frame.
+type WebpFrame struct {
+	Chunk WebpChunk
+	// X and Y are the frame offset on the canvas, in pixels.
+	X, Y          uint32
+	Width, Height uint32
+	// Duration is how long the frame is shown, in milliseconds.
+	Duration uint32
+	// Blend reports whether the frame is alpha-blended onto the canvas
+	// rather than overwriting it.
+	Blend bool
+	// DisposeToBackground reports whether the frame area is cleared to the
+	// background colour before the next frame is rendered.
+	DisposeToBackground bool
+}
+
+// Frames decodes the headers of the ANMF frames in data, which must be the
+// bytes the inspection was made from.
+func (i WebpInspection) Frames(data []byte) []WebpFrame {
+	var frames []WebpFrame
+	for _, c := range i.Chunks {
+		if c.FourCC != "ANMF" || c.Size < 16 || c.PayloadOffset()+16 > uint64(len(data)) {
+			continue
+		}
+
+		p := data[c.PayloadOffset() : c.PayloadOffset()+16]
+		frames = append(frames, WebpFrame{
+			Chunk:               c,
+			X:                   2 * ReadUint24(p[0:3]),
+			Y:                   2 * ReadUint24(p[3:6]),
+			Width:               ReadUint24(p[6:9]) + 1,
+			Height:              ReadUint24(p[9:12]) + 1,
+			Duration:            ReadUint24(p[12:15]),
+			Blend:               p[15]&0x02 == 0,
+			DisposeToBackground: p[15]&0x01 != 0,
+		})
+	}
+	return frames
+}
+
+// ReadUint24 decodes the little-endian 24-bit integers of the VP8X and
+// ANMF chunk fields from the first three bytes of b.
+func ReadUint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
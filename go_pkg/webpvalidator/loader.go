@@ -0,0 +1,177 @@
+package webpvalidator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// NativeLibraryEnv names the environment variable that points the loader
+// at a specific native library file.
+const NativeLibraryEnv = "WEBP_VALIDATOR_LIB"
+
+// The native library is loaded at runtime from the first source that
+// works, in this order:
+//
+//  1. the path given to SetNativeLibraryPath
+//  2. the path in $WEBP_VALIDATOR_LIB
+//  3. the library embedded with the webp_embed build tag, extracted to
+//     the user cache directory
+//  4. next to the executable, or in ../lib relative to it
+//  5. the platform's default search (LD_LIBRARY_PATH, ld.so.cache and the
+//     system directories on Linux; the DLL search order on Windows)
+//
+// An explicit path, from either of the first two sources, is the only
+// candidate tried: a misconfigured path is an error rather than a silent
+// fallback to some other copy of the library.
+const (
+	sourceExplicit   = "SetNativeLibraryPath"
+	sourceEnv        = NativeLibraryEnv
+	sourceEmbedded   = "embedded"
+	sourceExecutable = "executable directory"
+	sourceSystem     = "system search path"
+)
+
+// libraryCandidate is one place the loader will try.
+type libraryCandidate struct {
+	source string
+	path   string
+}
+
+// embeddedLibrary holds the native library when built with the webp_embed
+// tag (see embed_linux.go and embed_windows.go), and is nil otherwise.
+var embeddedLibrary []byte
+
+var loader struct {
+	mu       sync.Mutex
+	explicit string
+	loaded   bool
+	path     string
+	err      error
+}
+
+// SetNativeLibraryPath makes path the only library the loader will try.
+// It must be called before the first validation; once a library has been
+// loaded, or loading has failed, it returns an error.
+func SetNativeLibraryPath(path string) error {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	if loader.loaded || loader.err != nil {
+		return errors.New("native library already loaded")
+	}
+	loader.explicit = path
+	return nil
+}
+
+// LoadNativeLibrary loads the native library if it is not loaded yet and
+// returns the path it was loaded from. Validation calls it implicitly;
+// calling it at startup surfaces loader failures before the first request.
+// The outcome, success or failure, is final for the life of the process.
+func LoadNativeLibrary() (string, error) {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	if loader.loaded || loader.err != nil {
+		return loader.path, loader.err
+	}
+
+	var attempts []string
+	for _, candidate := range libraryCandidates(loader.explicit) {
+		path := candidate.path
+		if candidate.source == sourceEmbedded {
+			extracted, err := extractEmbeddedLibrary(embeddedLibrary)
+			if err != nil {
+				attempts = append(attempts, fmt.Sprintf("%s: %v", candidate.source, err))
+				continue
+			}
+			path = extracted
+		}
+
+		if err := openNativeLibrary(path); err != nil {
+			attempts = append(attempts, fmt.Sprintf("%s (%s): %v", candidate.source, path, err))
+			continue
+		}
+		loader.loaded = true
+		loader.path = path
+		return path, nil
+	}
+
+	loader.err = fmt.Errorf("failed to load native library %s: %s", nativeLibraryName, strings.Join(attempts, "; "))
+	return "", loader.err
+}
+
+// libraryCandidates returns the places to try, in resolution order.
+func libraryCandidates(explicit string) []libraryCandidate {
+	if explicit != "" {
+		return []libraryCandidate{{sourceExplicit, explicit}}
+	}
+	if path := os.Getenv(NativeLibraryEnv); path != "" {
+		return []libraryCandidate{{sourceEnv, path}}
+	}
+
+	var candidates []libraryCandidate
+	if len(embeddedLibrary) > 0 {
+		candidates = append(candidates, libraryCandidate{source: sourceEmbedded})
+	}
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		for _, path := range []string{
+			filepath.Join(dir, nativeLibraryName),
+			filepath.Join(dir, "..", "lib", nativeLibraryName),
+		} {
+			if _, err := os.Stat(path); err == nil {
+				candidates = append(candidates, libraryCandidate{sourceExecutable, path})
+			}
+		}
+	}
+	// A bare file name makes the platform loader apply its default search.
+	return append(candidates, libraryCandidate{sourceSystem, nativeLibraryName})
+}
+
+// extractEmbeddedLibrary writes data to a content-addressed path under the
+// user cache directory and returns that path. An existing extraction is
+// reused, so only the first process after an upgrade pays for the write.
+func extractEmbeddedLibrary(data []byte) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	dir := filepath.Join(cache, "webp-validator", hex.EncodeToString(sum[:8]))
+	path := filepath.Join(dir, nativeLibraryName)
+	if stat, err := os.Stat(path); err == nil && stat.Size() == int64(len(data)) {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to extract native library: %w", err)
+	}
+	// Write to a temporary name and rename, so a concurrent process never
+	// loads a partially written library.
+	tmp, err := os.CreateTemp(dir, nativeLibraryName+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to extract native library: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to extract native library: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,249 @@
+package webpvalidator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryCandidatesOrder(t *testing.T) {
+	t.Setenv(NativeLibraryEnv, "")
+	saved := embeddedLibrary
+	defer func() { embeddedLibrary = saved }()
+
+	embeddedLibrary = nil
+	candidates := libraryCandidates("")
+	assert.Equal(t, libraryCandidate{sourceSystem, nativeLibraryName}, candidates[len(candidates)-1])
+
+	embeddedLibrary = []byte("library")
+	candidates = libraryCandidates("")
+	assert.Equal(t, sourceEmbedded, candidates[0].source)
+
+	t.Setenv(NativeLibraryEnv, "/env/lib.so")
+	assert.Equal(t, []libraryCandidate{{sourceEnv, "/env/lib.so"}}, libraryCandidates(""))
+	assert.Equal(t, []libraryCandidate{{sourceExplicit, "/explicit/lib.so"}}, libraryCandidates("/explicit/lib.so"))
+}
+
+func TestExtractEmbeddedLibrary(t *testing.T) {
+	t.Setenv(cacheDirEnv(), t.TempDir())
+
+	path, err := extractEmbeddedLibrary([]byte("first"))
+	require.NoError(t, err)
+	assert.Equal(t, nativeLibraryName, filepath.Base(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(data))
+
+	again, err := extractEmbeddedLibrary([]byte("first"))
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+
+	other, err := extractEmbeddedLibrary([]byte("second"))
+	require.NoError(t, err)
+	assert.NotEqual(t, path, other)
+}
+
+// TestLoaderLayouts runs a fresh process per deployment layout, since a
+// process can load the native library only once.
+func TestLoaderLayouts(t *testing.T) {
+	library := findTestLibrary(t)
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	t.Run("explicit", func(t *testing.T) {
+		dir := t.TempDir()
+		path := copyFile(t, library, filepath.Join(dir, "custom", nativeLibraryName))
+		out := runLoaderHelper(t, exe, map[string]string{"WEBP_LOADER_EXPLICIT": path})
+		assert.Contains(t, out, "path="+path)
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("env", func(t *testing.T) {
+		dir := t.TempDir()
+		path := copyFile(t, library, filepath.Join(dir, nativeLibraryName))
+		out := runLoaderHelper(t, exe, map[string]string{NativeLibraryEnv: path})
+		assert.Contains(t, out, "path="+path)
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("explicit overrides env without fallback", func(t *testing.T) {
+		dir := t.TempDir()
+		path := copyFile(t, library, filepath.Join(dir, nativeLibraryName))
+		out := runLoaderHelper(t, exe, map[string]string{
+			NativeLibraryEnv:       path,
+			"WEBP_LOADER_EXPLICIT": filepath.Join(dir, "missing", nativeLibraryName),
+		})
+		assert.Contains(t, out, "error=failed to load native library")
+		assert.Contains(t, out, sourceExplicit)
+		assert.NotContains(t, out, sourceSystem)
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		cache := t.TempDir()
+		out := runLoaderHelper(t, exe, map[string]string{
+			"WEBP_LOADER_EMBED": library,
+			cacheDirEnv():       cache,
+		})
+		assert.Contains(t, out, "path="+cache)
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("executable lib dir", func(t *testing.T) {
+		dir := t.TempDir()
+		copied := copyFile(t, exe, filepath.Join(dir, "bin", filepath.Base(exe)))
+		path := copyFile(t, library, filepath.Join(dir, "lib", nativeLibraryName))
+		out := runLoaderHelper(t, copied, nil)
+		assert.Contains(t, out, "path="+path)
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("executable dir", func(t *testing.T) {
+		dir := t.TempDir()
+		copied := copyFile(t, exe, filepath.Join(dir, filepath.Base(exe)))
+		path := copyFile(t, library, filepath.Join(dir, nativeLibraryName))
+		out := runLoaderHelper(t, copied, nil)
+		assert.Contains(t, out, "path="+path)
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("system search path", func(t *testing.T) {
+		dir := t.TempDir()
+		copyFile(t, library, filepath.Join(dir, nativeLibraryName))
+		name, value := systemSearchEnv(dir)
+		out := runLoaderHelper(t, exe, map[string]string{name: value})
+		assert.Contains(t, out, "path="+nativeLibraryName)
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("replay without library", func(t *testing.T) {
+		fixture := filepath.Join(t.TempDir(), "fixture.json")
+		recorder := NewRecorder(NativeBackend)
+		recorder.Validate(sampleLossy)
+		require.NoError(t, recorder.Save(fixture))
+
+		out := runLoaderHelper(t, exe, map[string]string{"WEBP_LOADER_REPLAY": fixture})
+		assert.Contains(t, out, "error=failed to load native library")
+		assert.Contains(t, out, "valid=true")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		out := runLoaderHelper(t, exe, nil)
+		assert.Contains(t, out, "error=failed to load native library "+nativeLibraryName)
+		assert.Contains(t, out, sourceSystem)
+		assert.Contains(t, out, "valid=false")
+	})
+}
+
+// TestLoaderHelperProcess is the child side of TestLoaderLayouts.
+func TestLoaderHelperProcess(t *testing.T) {
+	if os.Getenv("WEBP_LOADER_HELPER") != "1" {
+		t.Skip("helper process for TestLoaderLayouts")
+	}
+
+	if path := os.Getenv("WEBP_LOADER_EXPLICIT"); path != "" {
+		require.NoError(t, SetNativeLibraryPath(path))
+	}
+	if path := os.Getenv("WEBP_LOADER_EMBED"); path != "" {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		embeddedLibrary = data
+	}
+
+	if path := os.Getenv("WEBP_LOADER_REPLAY"); path != "" {
+		stop, err := UseFixture(path)
+		require.NoError(t, err)
+		defer stop()
+	}
+
+	path, err := LoadNativeLibrary()
+	if err != nil {
+		fmt.Printf("error=%v\n", err)
+	} else {
+		fmt.Printf("path=%s\n", path)
+	}
+	fmt.Printf("valid=%v\n", ValidateWebp(sampleLossy).IsValid)
+	assert.Error(t, SetNativeLibraryPath("late"))
+}
+
+// runLoaderHelper runs exe as a loader helper process with an environment
+// stripped of every loader source except those in env.
+func runLoaderHelper(t *testing.T, exe string, env map[string]string) string {
+	t.Helper()
+
+	cmd := exec.Command(exe, "-test.run=^TestLoaderHelperProcess$", "-test.v")
+	searchName, _ := systemSearchEnv("")
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name != NativeLibraryEnv && name != searchName && name != cacheDirEnv() {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, "WEBP_LOADER_HELPER=1")
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+// findTestLibrary locates the built native library, skipping the test if
+// there is none.
+func findTestLibrary(t *testing.T) string {
+	t.Helper()
+
+	dirs := []string{filepath.Join("..", "..", "lib")}
+	name, _ := systemSearchEnv("")
+	dirs = append(dirs, filepath.SplitList(os.Getenv(name))...)
+	candidates := []string{os.Getenv(NativeLibraryEnv)}
+	for _, dir := range dirs {
+		candidates = append(candidates, filepath.Join(dir, nativeLibraryName))
+	}
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			abs, err := filepath.Abs(path)
+			require.NoError(t, err)
+			return abs
+		}
+	}
+	t.Skipf("%s not found; build the native library first", nativeLibraryName)
+	return ""
+}
+
+// systemSearchEnv returns the variable the platform loader searches, set
+// to dir.
+func systemSearchEnv(dir string) (string, string) {
+	if runtime.GOOS == "windows" {
+		return "PATH", dir
+	}
+	return "LD_LIBRARY_PATH", dir
+}
+
+// cacheDirEnv returns the variable os.UserCacheDir honours first.
+func cacheDirEnv() string {
+	if runtime.GOOS == "windows" {
+		return "LocalAppData"
+	}
+	return "XDG_CACHE_HOME"
+}
+
+func copyFile(t *testing.T, src, dst string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0o755))
+	require.NoError(t, os.WriteFile(dst, data, 0o755))
+	return dst
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package webpvalidator
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of file shared and writable.
+func mapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package webpvalidator
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile maps the first size bytes of file shared and writable. The
+// mapping object can be closed once the view exists; the view keeps it.
+func mapFile(file *os.File, size int) ([]byte, error) {
+	mapping, err := syscall.CreateFileMapping(syscall.Handle(file.Fd()), nil, syscall.PAGE_READWRITE,
+		uint32(uint64(size)>>32), uint32(size), nil)
+	if err != nil {
+		return nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+	defer syscall.CloseHandle(mapping)
+
+	addr, err := syscall.MapViewOfFile(mapping, syscall.FILE_MAP_WRITE, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(addr)), size), nil
+}
+
+func unmapFile(data []byte) error {
+	return os.NewSyscallError("UnmapViewOfFile", syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&data[0]))))
+}
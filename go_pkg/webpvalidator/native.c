@@ -0,0 +1,85 @@
+#include "native.h"
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+static WebpValidationResult (*p_validate_webp_ffi)(const uint8_t *, size_t);
+static void (*p_free_error_message)(char *);
+static WebpInspectionResult (*p_inspect_webp_ffi)(const uint8_t *, size_t);
+static void (*p_free_webp_inspection)(WebpInspectionResult);
+static char *(*p_decode_webp_ffi)(const uint8_t *, size_t, uint8_t *, size_t, uint32_t *, size_t);
+static uint32_t (*p_webp_supported_features_ffi)(void);
+static const char *(*p_webp_validator_version_ffi)(void);
+
+static void *resolve(void *handle, const char *name, char **error)
+{
+    void *symbol = webp_native_platform_symbol(handle, name);
+    if (symbol == NULL && *error == NULL)
+    {
+        const char *format = "missing symbol %s";
+        size_t size = strlen(format) + strlen(name);
+        *error = malloc(size);
+        if (*error != NULL)
+        {
+            snprintf(*error, size, format, name);
+        }
+    }
+    return symbol;
+}
+
+int webp_native_open(const char *path, char **error)
+{
+    *error = NULL;
+    void *handle = webp_native_platform_open(path, error);
+    if (handle == NULL)
+    {
+        return 0;
+    }
+
+    p_validate_webp_ffi = resolve(handle, "validate_webp_ffi", error);
+    p_free_error_message = resolve(handle, "free_error_message", error);
+    p_inspect_webp_ffi = resolve(handle, "inspect_webp_ffi", error);
+    p_free_webp_inspection = resolve(handle, "free_webp_inspection", error);
+    p_decode_webp_ffi = resolve(handle, "decode_webp_ffi", error);
+    /* Also proves WebpValidationResult has its features field. */
+    p_webp_supported_features_ffi = resolve(handle, "webp_supported_features_ffi", error);
+    p_webp_validator_version_ffi = resolve(handle, "webp_validator_version_ffi", error);
+    return *error == NULL;
+}
+
+WebpValidationResult webp_native_validate(const uint8_t *data, size_t len)
+{
+    return p_validate_webp_ffi(data, len);
+}
+
+void webp_native_free_error_message(char *error_message)
+{
+    p_free_error_message(error_message);
+}
+
+WebpInspectionResult webp_native_inspect(const uint8_t *data, size_t len)
+{
+    return p_inspect_webp_ffi(data, len);
+}
+
+void webp_native_free_inspection(WebpInspectionResult result)
+{
+    p_free_webp_inspection(result);
+}
+
+char *webp_native_decode(const uint8_t *data, size_t len, uint8_t *pixels, size_t pixels_len,
+                         uint32_t *durations, size_t num_frames)
+{
+    return p_decode_webp_ffi(data, len, pixels, pixels_len, durations, num_frames);
+}
+
+uint32_t webp_native_supported_features(void)
+{
+    return p_webp_supported_features_ffi();
+}
+
+const char *webp_native_version(void)
+{
+    return p_webp_validator_version_ffi();
+}
@@ -0,0 +1,35 @@
+#ifndef WEBP_VALIDATOR_NATIVE_H
+#define WEBP_VALIDATOR_NATIVE_H
+
+#include <stddef.h>
+
+#include "../../include/webp_validator.h"
+
+/*
+ * Runtime binding to the native library. The library is loaded with
+ * webp_native_open instead of being linked, so the resolution order is
+ * decided by Go code (see loader.go) rather than by the dynamic linker.
+ */
+
+/*
+ * Load the library at path (UTF-8) and resolve every symbol. A path
+ * without a directory separator is searched using the platform's default
+ * library search order. Returns 1 on success; on failure returns 0 and
+ * sets *error to a message the caller must free().
+ */
+int webp_native_open(const char *path, char **error);
+
+/* Implemented by the platform loader (native_linux.c, native_windows.c). */
+void *webp_native_platform_open(const char *path, char **error);
+void *webp_native_platform_symbol(void *handle, const char *name);
+
+WebpValidationResult webp_native_validate(const uint8_t *data, size_t len);
+void webp_native_free_error_message(char *error_message);
+WebpInspectionResult webp_native_inspect(const uint8_t *data, size_t len);
+void webp_native_free_inspection(WebpInspectionResult result);
+char *webp_native_decode(const uint8_t *data, size_t len, uint8_t *pixels, size_t pixels_len,
+                         uint32_t *durations, size_t num_frames);
+uint32_t webp_native_supported_features(void);
+const char *webp_native_version(void);
+
+#endif
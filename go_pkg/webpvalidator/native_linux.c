@@ -0,0 +1,21 @@
+#include "native.h"
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+void *webp_native_platform_open(const char *path, char **error)
+{
+    void *handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+    if (handle == NULL)
+    {
+        const char *message = dlerror();
+        *error = strdup(message != NULL ? message : "dlopen failed");
+    }
+    return handle;
+}
+
+void *webp_native_platform_symbol(void *handle, const char *name)
+{
+    return dlsym(handle, name);
+}
@@ -0,0 +1,47 @@
+#include "native.h"
+
+#include <stdlib.h>
+#include <string.h>
+#include <windows.h>
+
+static char *last_error_message(void)
+{
+    char *message = NULL;
+    DWORD length = FormatMessageA(
+        FORMAT_MESSAGE_ALLOCATE_BUFFER | FORMAT_MESSAGE_FROM_SYSTEM | FORMAT_MESSAGE_IGNORE_INSERTS,
+        NULL, GetLastError(), 0, (LPSTR)&message, 0, NULL);
+    if (length == 0)
+    {
+        return _strdup("LoadLibrary failed");
+    }
+
+    char *copy = _strdup(message);
+    LocalFree(message);
+    return copy;
+}
+
+void *webp_native_platform_open(const char *path, char **error)
+{
+    // Paths are UTF-8; LoadLibraryA would interpret them in the ANSI code page.
+    int length = MultiByteToWideChar(CP_UTF8, 0, path, -1, NULL, 0);
+    wchar_t *wide = malloc(length * sizeof(wchar_t));
+    if (wide == NULL)
+    {
+        *error = _strdup("out of memory");
+        return NULL;
+    }
+    MultiByteToWideChar(CP_UTF8, 0, path, -1, wide, length);
+
+    HMODULE handle = LoadLibraryW(wide);
+    free(wide);
+    if (handle == NULL)
+    {
+        *error = last_error_message();
+    }
+    return handle;
+}
+
+void *webp_native_platform_symbol(void *handle, const char *name)
+{
+    return (void *)GetProcAddress((HMODULE)handle, name);
+}
@@ -0,0 +1,166 @@
+package webpvalidator
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Sample sizes for derived results: both are insensitive to detail, so
+// they are computed on a small thumbnail rather than the full frame.
+const (
+	placeholderSampleSize = 32
+	dominantSampleSize    = 64
+)
+
+// scaleDown box-filters img so its longer side is at most maxSize.
+func scaleDown(img *image.RGBA, maxSize int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	tw, th := maxSize, maxSize
+	if w > h {
+		th = max(1, h*maxSize/w)
+	} else {
+		tw = max(1, w*maxSize/h)
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, (ty+1)*h/th
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, (tx+1)*w/tw
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				row := img.Pix[(y-img.Rect.Min.Y)*img.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[(x-img.Rect.Min.X)*4:]
+					sum[0] += int(p[0])
+					sum[1] += int(p[1])
+					sum[2] += int(p[2])
+					sum[3] += int(p[3])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			i := thumb.PixOffset(tx, ty)
+			for c := range sum {
+				thumb.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return thumb
+}
+
+// dominantColor implements Decoded.DominantColor.
+func dominantColor(img *image.RGBA) color.RGBA {
+	type bucket struct{ count, r, g, b int }
+	var buckets [4096]bucket
+	best := -1
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		p := img.Pix[i : i+4]
+		if p[3] < 128 {
+			continue
+		}
+		key := int(p[0]>>4)<<8 | int(p[1]>>4)<<4 | int(p[2]>>4)
+		b := &buckets[key]
+		b.count++
+		b.r += int(p[0])
+		b.g += int(p[1])
+		b.b += int(p[2])
+		if best < 0 || b.count > buckets[best].count {
+			best = key
+		}
+	}
+	if best < 0 {
+		return color.RGBA{}
+	}
+
+	b := buckets[best]
+	return color.RGBA{uint8(b.r / b.count), uint8(b.g / b.count), uint8(b.b / b.count), 0xff}
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// blurHash encodes img with nx by ny components, following the reference
+// algorithm at https://github.com/woltapp/blurhash.
+func blurHash(img *image.RGBA, nx, ny int) string {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	factors := make([][3]float64, 0, nx*ny)
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var f [3]float64
+			for y := 0; y < h; y++ {
+				cy := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+				for x := 0; x < w; x++ {
+					basis := normalisation * cy * math.Cos(math.Pi*float64(i)*float64(x)/float64(w))
+					p := img.Pix[img.PixOffset(x+img.Rect.Min.X, y+img.Rect.Min.Y):]
+					f[0] += basis * srgbToLinear(p[0])
+					f[1] += basis * srgbToLinear(p[1])
+					f[2] += basis * srgbToLinear(p[2])
+				}
+			}
+			scale := 1 / float64(w*h)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	encodeBase83(&hash, (nx-1)+(ny-1)*9, 1)
+
+	maximum := 1.0
+	if len(factors) > 1 {
+		actual := 0.0
+		for _, f := range factors[1:] {
+			actual = math.Max(actual, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actual*166-0.5))))
+		maximum = float64(quantised+1) / 166
+		encodeBase83(&hash, quantised, 1)
+	} else {
+		encodeBase83(&hash, 0, 1)
+	}
+
+	dc := factors[0]
+	encodeBase83(&hash, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+	for _, f := range factors[1:] {
+		quant := func(v float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximum, 0.5)*9+9.5))))
+		}
+		encodeBase83(&hash, quant(f[0])*19*19+quant(f[1])*19+quant(f[2]), 2)
+	}
+	return hash.String()
+}
+
+func encodeBase83(b *strings.Builder, value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		digit := value / int(math.Pow(83, float64(i))) % 83
+		b.WriteByte(base83Chars[digit])
+	}
+}
+
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
@@ -0,0 +1,156 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PolicyFileName is the per-directory policy file. A policy applies to its
+// directory and everything below it; nested policy files override the
+// fields they set and inherit the rest.
+const PolicyFileName = ".webp-policy.json"
+
+// AssetPolicy limits what the images in a directory may look like.
+// Unset fields are not enforced.
+type AssetPolicy struct {
+	MaxWidth      *uint32 `json:"max_width,omitempty"`
+	MaxHeight     *uint32 `json:"max_height,omitempty"`
+	MaxBytes      *int64  `json:"max_bytes,omitempty"`
+	MaxFrames     *uint32 `json:"max_frames,omitempty"`
+	AllowAnimated *bool   `json:"allow_animated,omitempty"`
+	RequireAlpha  *bool   `json:"require_alpha,omitempty"`
+	// Ignore lists file name patterns (filepath.Match syntax) to skip.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// Merge returns p with every field set in child overriding it.
+func (p AssetPolicy) Merge(child AssetPolicy) AssetPolicy {
+	if child.MaxWidth != nil {
+		p.MaxWidth = child.MaxWidth
+	}
+	if child.MaxHeight != nil {
+		p.MaxHeight = child.MaxHeight
+	}
+	if child.MaxBytes != nil {
+		p.MaxBytes = child.MaxBytes
+	}
+	if child.MaxFrames != nil {
+		p.MaxFrames = child.MaxFrames
+	}
+	if child.AllowAnimated != nil {
+		p.AllowAnimated = child.AllowAnimated
+	}
+	if child.RequireAlpha != nil {
+		p.RequireAlpha = child.RequireAlpha
+	}
+	if child.Ignore != nil {
+		p.Ignore = child.Ignore
+	}
+	return p
+}
+
+// Ignores reports whether name matches one of the policy's ignore patterns.
+func (p AssetPolicy) Ignores(name string) bool {
+	for _, pattern := range p.Ignore {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns one message per policy violation.
+func (p AssetPolicy) Check(info WebpInfo, size int64) []string {
+	var violations []string
+	if p.MaxWidth != nil && info.Width > *p.MaxWidth {
+		violations = append(violations, fmt.Sprintf("width %d exceeds policy max_width %d", info.Width, *p.MaxWidth))
+	}
+	if p.MaxHeight != nil && info.Height > *p.MaxHeight {
+		violations = append(violations, fmt.Sprintf("height %d exceeds policy max_height %d", info.Height, *p.MaxHeight))
+	}
+	if p.MaxBytes != nil && size > *p.MaxBytes {
+		violations = append(violations, fmt.Sprintf("size %d bytes exceeds policy max_bytes %d", size, *p.MaxBytes))
+	}
+	if p.AllowAnimated != nil && !*p.AllowAnimated && info.Features.Has(FeatureAnimation) {
+		violations = append(violations, "animated webp not allowed by policy")
+	}
+	if p.MaxFrames != nil && info.NumFrames > *p.MaxFrames {
+		violations = append(violations, fmt.Sprintf("%d frames exceeds policy max_frames %d", info.NumFrames, *p.MaxFrames))
+	}
+	if p.RequireAlpha != nil && *p.RequireAlpha && !info.Features.Has(FeatureAlpha) {
+		violations = append(violations, "policy requires an alpha channel")
+	}
+	return violations
+}
+
+// PolicyResolver loads and merges policy files between a root directory
+// and the directories below it, reading each policy file once.
+type PolicyResolver struct {
+	root  string
+	cache map[string]AssetPolicy
+}
+
+// NewPolicyResolver returns a resolver for the policies under root.
+func NewPolicyResolver(root string) *PolicyResolver {
+	return &PolicyResolver{root: filepath.Clean(root), cache: make(map[string]AssetPolicy)}
+}
+
+// Resolve returns the effective policy for dir, which must be root or a
+// directory below it.
+func (r *PolicyResolver) Resolve(dir string) (AssetPolicy, error) {
+	dir = filepath.Clean(dir)
+	if policy, ok := r.cache[dir]; ok {
+		return policy, nil
+	}
+
+	var parent AssetPolicy
+	rel, err := filepath.Rel(r.root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return AssetPolicy{}, fmt.Errorf("%s is outside %s", dir, r.root)
+	}
+	if rel != "." {
+		if parent, err = r.Resolve(filepath.Dir(dir)); err != nil {
+			return AssetPolicy{}, err
+		}
+	}
+
+	policy := parent
+	data, err := os.ReadFile(filepath.Join(dir, PolicyFileName))
+	switch {
+	case err == nil:
+		own, err := parsePolicy(data)
+		if err != nil {
+			return AssetPolicy{}, fmt.Errorf("invalid policy file %s: %w", filepath.Join(dir, PolicyFileName), err)
+		}
+		policy = parent.Merge(own)
+	case !errors.Is(err, os.ErrNotExist):
+		return AssetPolicy{}, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	r.cache[dir] = policy
+	return policy, nil
+}
+
+// parsePolicy decodes a policy file. Unknown keys are errors rather than
+// ignored, so a misspelled limit cannot silently turn its check off.
+func parsePolicy(data []byte) (AssetPolicy, error) {
+	var policy AssetPolicy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policy); err != nil {
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return AssetPolicy{}, fmt.Errorf("unknown key %s", name)
+		}
+		return AssetPolicy{}, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return AssetPolicy{}, errors.New("unexpected data after the policy object")
+	}
+	return policy, nil
+}
@@ -0,0 +1,138 @@
+package webpvalidator
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Options configures a Pool.
+type Options struct {
+	// Workers is the number of concurrent validations. Zero means one per
+	// CPU in CPUSet, or runtime.NumCPU() if CPUSet is empty.
+	Workers int
+	// CPUSet pins every worker's OS thread to these CPUs. Validation runs
+	// in the native library on the calling thread, so this confines the
+	// native work too. Empty means no pinning.
+	CPUSet []int
+}
+
+// Pool runs validations on a fixed set of worker threads.
+type Pool struct {
+	workers int
+	jobs    chan func()
+	wg      sync.WaitGroup
+}
+
+// NewPool starts the workers described by opts. It fails if the CPU set
+// cannot be applied, before any work is accepted.
+func NewPool(opts Options) (*Pool, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = len(opts.CPUSet)
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	p := &Pool{workers: workers, jobs: make(chan func())}
+	started := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work(opts.CPUSet, started)
+	}
+
+	for i := 0; i < workers; i++ {
+		if err := <-started; err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// work runs jobs on a dedicated OS thread pinned to cpus.
+func (p *Pool) work(cpus []int, started chan<- error) {
+	defer p.wg.Done()
+
+	// The thread is never unlocked: once its affinity has changed it must
+	// not go back to running other goroutines, so it exits with us.
+	runtime.LockOSThread()
+	if len(cpus) > 0 {
+		if err := setThreadAffinity(cpus); err != nil {
+			started <- fmt.Errorf("failed to pin worker to cpus %v: %w", cpus, err)
+			return
+		}
+	}
+	started <- nil
+
+	for job := range p.jobs {
+		job()
+	}
+}
+
+// Workers returns the number of worker threads.
+func (p *Pool) Workers() int {
+	return p.workers
+}
+
+// Go runs fn on a worker, blocking until one is free.
+func (p *Pool) Go(fn func()) {
+	p.jobs <- fn
+}
+
+// Validate is ValidateWebp run on a worker.
+func (p *Pool) Validate(data []byte) WebpInfo {
+	done := make(chan WebpInfo, 1)
+	p.Go(func() { done <- ValidateWebp(data) })
+	return <-done
+}
+
+// Close waits for running jobs and stops the workers. The pool must not be
+// used afterwards.
+func (p *Pool) Close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// maxAffinityCPUs bounds the CPU numbers ParseCPUSet accepts; it is also
+// the size of the CPU mask passed to the Linux kernel.
+const maxAffinityCPUs = 1024
+
+// ParseCPUSet parses a CPU list such as "0-3,8,10-11", the format used by
+// taskset and /proc/<pid>/status. CPU numbers must be below 1024.
+func ParseCPUSet(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 0 || last < first {
+			return nil, fmt.Errorf("invalid cpu set %q", s)
+		}
+		if last >= maxAffinityCPUs {
+			return nil, fmt.Errorf("invalid cpu set %q: cpu %d out of range", s, last)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			seen[cpu] = true
+		}
+	}
+
+	cpus := make([]int, 0, len(seen))
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
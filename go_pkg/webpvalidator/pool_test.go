@@ -0,0 +1,82 @@
+package webpvalidator
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUSet(t *testing.T) {
+	cpus, err := ParseCPUSet("8, 0-3,2,10-11")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 8, 10, 11}, cpus)
+
+	cpus, err = ParseCPUSet("1023")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1023}, cpus)
+
+	for _, input := range []string{"a", "3-1", "-1", "1-b", "1024", "0-9223372036854775807"} {
+		_, err := ParseCPUSet(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestPoolValidate(t *testing.T) {
+	pool, err := NewPool(Options{Workers: 4})
+	require.NoError(t, err)
+	defer pool.Close()
+	assert.Equal(t, 4, pool.Workers())
+
+	data := mustFixtureData(t, FixtureAnimated)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info := pool.Validate(data)
+			assert.True(t, info.IsValid)
+			assert.True(t, info.IsAnimated)
+		}()
+	}
+	wg.Wait()
+}
+
+// threadCPUs returns the Cpus_allowed_list of the calling thread.
+func threadCPUs() string {
+	status, err := os.ReadFile("/proc/thread-self/status")
+	if err != nil {
+		return err.Error()
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if list, ok := strings.CutPrefix(line, "Cpus_allowed_list:"); ok {
+			return strings.TrimSpace(list)
+		}
+	}
+	return "no Cpus_allowed_list in thread status"
+}
+
+func TestPoolCPUSet(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("thread affinity is only observable via /proc on linux")
+	}
+
+	pool, err := NewPool(Options{CPUSet: []int{0}})
+	require.NoError(t, err)
+	defer pool.Close()
+	assert.Equal(t, 1, pool.Workers(), "workers should default to the cpu set size")
+
+	done := make(chan string)
+	pool.Go(func() { done <- threadCPUs() })
+	assert.Equal(t, "0", <-done, "worker thread should be pinned")
+}
+
+func TestPoolCPUSetInvalid(t *testing.T) {
+	_, err := NewPool(Options{CPUSet: []int{1 << 20}})
+	assert.ErrorContains(t, err, "failed to pin worker")
+}
@@ -0,0 +1,52 @@
+package webpvalidator
+
+import "os"
+
+// PrefetchedFile is one file read by PrefetchFiles, with the results of
+// ReadWebpFile.
+type PrefetchedFile struct {
+	Data     []byte
+	Snapshot FileSnapshot
+	Err      error
+}
+
+// PrefetchFiles reads paths in order on a background goroutine so disk
+// reads overlap validation instead of alternating with it. Reads are
+// double buffered: while the caller works on one file, the next is read
+// into memory and waits, and the one after that is already being pulled
+// into the page cache by a read-ahead hint. With a throttle there is no
+// hint, since the kernel's reads would not be counted against it. The
+// caller must receive one result per path; the channel is closed after
+// the last.
+func PrefetchFiles(paths []string, throttle *Throttle) <-chan PrefetchedFile {
+	files := make(chan PrefetchedFile)
+	go func() {
+		defer close(files)
+		for i, path := range paths {
+			if throttle == nil && i+1 < len(paths) {
+				hintWillNeed(paths[i+1])
+			}
+			data, snapshot, err := ReadWebpFile(path, throttle)
+			files <- PrefetchedFile{data, snapshot, err}
+		}
+	}()
+	return files
+}
+
+// hintWillNeed asks the kernel to start reading path in the background.
+// Files that cannot be opened are skipped; reading them reports the error.
+func hintWillNeed(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	readAhead.willNeed(f)
+	f.Close()
+}
+
+// readAhead gives the kernel the read-ahead hints of ReadWebpFile and
+// PrefetchFiles; tests replace it to see which are given.
+var readAhead = struct {
+	sequential func(*os.File)
+	willNeed   func(*os.File)
+}{adviseSequential, adviseWillNeed}
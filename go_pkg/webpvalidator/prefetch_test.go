@@ -0,0 +1,69 @@
+package webpvalidator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetchFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for name, data := range map[string][]byte{"a.webp": sampleLossy, "b.webp": sampleAlpha, "c.webp": sampleLossless} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.webp"), paths[0])
+
+	var results []PrefetchedFile
+	for file := range PrefetchFiles(paths, nil) {
+		results = append(results, file)
+	}
+	require.Len(t, results, len(paths))
+
+	for i, path := range paths {
+		want, err := os.ReadFile(path)
+		if err != nil {
+			assert.ErrorContains(t, results[i].Err, "failed to read file", path)
+			continue
+		}
+		require.NoError(t, results[i].Err, path)
+		assert.Equal(t, want, results[i].Data, "results arrive in path order")
+		assert.NoError(t, results[i].Snapshot.Verify())
+	}
+
+	_, ok := <-PrefetchFiles(nil, nil)
+	assert.False(t, ok)
+}
+
+func TestPrefetchFilesThrottledGivesNoHints(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.webp", "b.webp", "c.webp"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, sampleLossy, 0o644))
+		paths = append(paths, path)
+	}
+
+	var sequential, willNeed int
+	saved := readAhead
+	readAhead.sequential = func(*os.File) { sequential++ }
+	readAhead.willNeed = func(*os.File) { willNeed++ }
+	defer func() { readAhead = saved }()
+
+	for range PrefetchFiles(paths, nil) {
+	}
+	assert.Equal(t, 3, sequential)
+	assert.Equal(t, 2, willNeed)
+
+	sequential, willNeed = 0, 0
+	for file := range PrefetchFiles(paths, NewThrottle(1<<30)) {
+		require.NoError(t, file.Err)
+	}
+	assert.Zero(t, sequential, "throttled reads should not widen readahead")
+	assert.Zero(t, willNeed, "throttled scans should not prefetch ahead of the throttle")
+}
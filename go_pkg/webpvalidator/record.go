@@ -0,0 +1,285 @@
+package webpvalidator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RecordEnv names the environment variable that makes UseFixture record
+// against the native library instead of replaying.
+const RecordEnv = "WEBP_VALIDATOR_RECORD"
+
+// fixtureVersion is bumped when the fixture file layout or the meaning of
+// a recorded field changes. Version 2 records WebpInfo.Features, which
+// version 1 fixtures lack, so replaying one would report no features.
+// Version 3 adds decoded pixels and the supported feature mask, version 4
+// the library version.
+const fixtureVersion = 4
+
+// fixtureFile is the on-disk form of a recording. Entries are keyed by the
+// hex SHA-256 of the input; encoding/json sorts map keys, so re-recording
+// the same inputs produces the same file.
+type fixtureFile struct {
+	Version           int                     `json:"version"`
+	SupportedFeatures *fixtureFeatures        `json:"supported_features,omitempty"`
+	LibraryVersion    *fixtureLibraryVersion  `json:"library_version,omitempty"`
+	Entries           map[string]fixtureEntry `json:"entries"`
+}
+
+type fixtureEntry struct {
+	Validate *WebpInfo       `json:"validate,omitempty"`
+	Inspect  *WebpInspection `json:"inspect,omitempty"`
+	Decode   *fixtureDecode  `json:"decode,omitempty"`
+}
+
+type fixtureFeatures struct {
+	Features Features `json:"features"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type fixtureLibraryVersion struct {
+	Version string `json:"version"`
+	Error   string `json:"error,omitempty"`
+}
+
+// fixtureDecode holds the most frames decoded from one input; a replay
+// can serve any shorter leading run of them, since frames are stored back
+// to back.
+type fixtureDecode struct {
+	Pixels    []byte   `json:"pixels,omitempty"`
+	Durations []uint32 `json:"durations,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func inputKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Recorder is a Backend that forwards to another backend and remembers
+// every result, for Save to write out as a fixture file.
+type Recorder struct {
+	next     Backend
+	mu       sync.Mutex
+	entries  map[string]fixtureEntry
+	features *fixtureFeatures
+	version  *fixtureLibraryVersion
+}
+
+// NewRecorder returns a Recorder forwarding to next, usually NativeBackend.
+func NewRecorder(next Backend) *Recorder {
+	return &Recorder{next: next, entries: make(map[string]fixtureEntry)}
+}
+
+func (r *Recorder) Validate(data []byte) WebpInfo {
+	info := r.next.Validate(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := inputKey(data)
+	entry := r.entries[key]
+	entry.Validate = &info
+	r.entries[key] = entry
+	return info
+}
+
+func (r *Recorder) Inspect(data []byte) WebpInspection {
+	inspection := r.next.Inspect(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := inputKey(data)
+	entry := r.entries[key]
+	entry.Inspect = &inspection
+	r.entries[key] = entry
+	return inspection
+}
+
+func (r *Recorder) Decode(data, pixels []byte, durations []uint32) error {
+	err := r.next.Decode(data, pixels, durations)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := inputKey(data)
+	entry := r.entries[key]
+	if entry.Decode != nil && entry.Decode.Error == "" && len(entry.Decode.Durations) >= len(durations) {
+		return err
+	}
+	if err != nil {
+		entry.Decode = &fixtureDecode{Error: err.Error()}
+	} else {
+		entry.Decode = &fixtureDecode{
+			Pixels:    append([]byte(nil), pixels...),
+			Durations: append([]uint32(nil), durations...),
+		}
+	}
+	r.entries[key] = entry
+	return err
+}
+
+func (r *Recorder) SupportedFeatures() (Features, error) {
+	features, err := r.next.SupportedFeatures()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.features = &fixtureFeatures{Features: features, Error: errorString(err)}
+	return features, err
+}
+
+func (r *Recorder) Version() (string, error) {
+	version, err := r.next.Version()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version = &fixtureLibraryVersion{Version: version, Error: errorString(err)}
+	return version, err
+}
+
+// Save writes everything recorded so far to path.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(fixtureFile{
+		Version:           fixtureVersion,
+		SupportedFeatures: r.features,
+		LibraryVersion:    r.version,
+		Entries:           r.entries,
+	}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// Replay is a Backend that serves results from a fixture file written by a
+// Recorder, without touching the native library. Inputs that were not
+// recorded fail validation with an error naming their hash.
+type Replay struct {
+	entries  map[string]fixtureEntry
+	features *fixtureFeatures
+	version  *fixtureLibraryVersion
+}
+
+// LoadReplay reads a fixture file written by Recorder.Save.
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var file fixtureFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	if file.Version != fixtureVersion {
+		return nil, fmt.Errorf("unsupported fixture version %d in %s (want %d); re-record with %s=1",
+			file.Version, path, fixtureVersion, RecordEnv)
+	}
+	return &Replay{entries: file.Entries, features: file.SupportedFeatures, version: file.LibraryVersion}, nil
+}
+
+func (r *Replay) Validate(data []byte) WebpInfo {
+	if entry, ok := r.entries[inputKey(data)]; ok && entry.Validate != nil {
+		return *entry.Validate
+	}
+	return WebpInfo{Error: r.missing(data, "validation")}
+}
+
+func (r *Replay) Inspect(data []byte) WebpInspection {
+	if entry, ok := r.entries[inputKey(data)]; ok && entry.Inspect != nil {
+		return *entry.Inspect
+	}
+	return WebpInspection{Findings: []WebpFinding{{Message: r.missing(data, "inspection")}}}
+}
+
+func (r *Replay) Decode(data, pixels []byte, durations []uint32) error {
+	entry, ok := r.entries[inputKey(data)]
+	if !ok || entry.Decode == nil {
+		return errors.New(r.missing(data, "decode"))
+	}
+	recorded := entry.Decode
+	if recorded.Error != "" {
+		return errors.New(recorded.Error)
+	}
+	if len(durations) > len(recorded.Durations) || len(pixels) > len(recorded.Pixels) {
+		return fmt.Errorf("replay: recorded decode of input sha256:%s has %d frames, %d requested; re-record with %s=1",
+			inputKey(data), len(recorded.Durations), len(durations), RecordEnv)
+	}
+	if len(pixels) != len(recorded.Pixels)/len(recorded.Durations)*len(durations) {
+		return errors.New("decode buffers do not match the recorded frame size")
+	}
+	copy(pixels, recorded.Pixels)
+	copy(durations, recorded.Durations)
+	return nil
+}
+
+func (r *Replay) SupportedFeatures() (Features, error) {
+	if r.features == nil {
+		return 0, fmt.Errorf("replay: no recorded supported features; re-record with %s=1", RecordEnv)
+	}
+	if r.features.Error != "" {
+		return r.features.Features, errors.New(r.features.Error)
+	}
+	return r.features.Features, nil
+}
+
+func (r *Replay) Version() (string, error) {
+	if r.version == nil {
+		return "", fmt.Errorf("replay: no recorded library version; re-record with %s=1", RecordEnv)
+	}
+	if r.version.Error != "" {
+		return r.version.Version, errors.New(r.version.Error)
+	}
+	return r.version.Version, nil
+}
+
+func (r *Replay) missing(data []byte, kind string) string {
+	return fmt.Sprintf("replay: no recorded %s for input sha256:%s; re-record with %s=1", kind, inputKey(data), RecordEnv)
+}
+
+// UseFixture installs a Replay of path as the backend. With
+// WEBP_VALIDATOR_RECORD=1 it instead records calls to the native library,
+// and the returned function saves them to path. Either way, call the
+// returned function when done to restore the previous backend:
+//
+//	stop, err := UseFixture("testdata/webp.fixture.json")
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer stop()
+func UseFixture(path string) (func() error, error) {
+	if os.Getenv(RecordEnv) == "1" {
+		recorder := NewRecorder(NativeBackend)
+		previous := SetBackend(recorder)
+		return func() error {
+			SetBackend(previous)
+			return recorder.Save(path)
+		}, nil
+	}
+
+	replay, err := LoadReplay(path)
+	if err != nil {
+		return nil, err
+	}
+	previous := SetBackend(replay)
+	return func() error {
+		SetBackend(previous)
+		return nil
+	}, nil
+}
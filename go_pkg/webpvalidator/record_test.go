@@ -0,0 +1,170 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBackend answers every call with fixed results.
+type stubBackend struct {
+	info       WebpInfo
+	inspection WebpInspection
+	calls      int
+}
+
+func (s *stubBackend) Validate(data []byte) WebpInfo {
+	s.calls++
+	return s.info
+}
+
+func (s *stubBackend) Inspect(data []byte) WebpInspection {
+	s.calls++
+	return s.inspection
+}
+
+func (s *stubBackend) Decode(data, pixels []byte, durations []uint32) error {
+	s.calls++
+	for i := range pixels {
+		pixels[i] = 0xff
+	}
+	return nil
+}
+
+func (s *stubBackend) SupportedFeatures() (Features, error) {
+	s.calls++
+	return s.info.Features, nil
+}
+
+func (s *stubBackend) Version() (string, error) {
+	s.calls++
+	return "stub", nil
+}
+
+func TestSetBackend(t *testing.T) {
+	stub := &stubBackend{info: WebpInfo{IsValid: true, Width: 7}}
+	previous := SetBackend(stub)
+	defer SetBackend(previous)
+
+	assert.Equal(t, uint32(7), ValidateWebp([]byte("anything")).Width)
+	assert.Equal(t, uint32(7), ValidateWebpReader(bytes.NewReader([]byte("anything"))).Width)
+	assert.Equal(t, 2, stub.calls)
+
+	assert.Equal(t, stub, SetBackend(nil))
+	assert.False(t, ValidateWebp([]byte("anything")).IsValid)
+}
+
+func TestRecordReplay(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	paths := []string{mustFixturePath(t, FixtureAnimated), mustFixturePath(t, FixtureStatic), mustFixturePath(t, FixtureNotWebp)}
+
+	recorder := NewRecorder(NativeBackend)
+	want := make(map[string]WebpInfo)
+	wantInspection := make(map[string]WebpInspection)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		want[path] = recorder.Validate(data)
+		wantInspection[path] = recorder.Inspect(data)
+	}
+	require.NoError(t, recorder.Save(fixture))
+
+	replay, err := LoadReplay(fixture)
+	require.NoError(t, err)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want[path], replay.Validate(data), path)
+		assert.Equal(t, wantInspection[path], replay.Inspect(data), path)
+	}
+
+	info := replay.Validate([]byte("not recorded"))
+	assert.False(t, info.IsValid)
+	assert.Contains(t, info.Error, "replay: no recorded validation for input sha256:")
+	assert.Contains(t, replay.Inspect([]byte("not recorded")).Findings[0].Message, "no recorded inspection")
+}
+
+func TestRecordReplayDecode(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+
+	recorder := NewRecorder(NativeBackend)
+	previous := SetBackend(recorder)
+	decoded, err := OpenBytes(sampleAnimated)
+	require.NoError(t, err)
+	want, err := decoded.Frames()
+	require.NoError(t, err)
+	wantFeatures, err := SupportedFeatures()
+	require.NoError(t, err)
+	wantVersion, err := LibraryVersion()
+	require.NoError(t, err)
+	_, err = OpenBytes(sampleLossy)
+	require.NoError(t, err)
+	SetBackend(previous)
+	require.NoError(t, recorder.Save(fixture))
+
+	replay, err := LoadReplay(fixture)
+	require.NoError(t, err)
+	previous = SetBackend(replay)
+	defer SetBackend(previous)
+
+	decoded, err = OpenBytes(sampleAnimated)
+	require.NoError(t, err)
+	first, err := decoded.Image()
+	require.NoError(t, err)
+	assert.Equal(t, want[0].Image.Pix, first.Pix, "a shorter decode is served from the recorded frames")
+	frames, err := decoded.Frames()
+	require.NoError(t, err)
+	assert.Equal(t, want, frames)
+	features, err := SupportedFeatures()
+	require.NoError(t, err)
+	assert.Equal(t, wantFeatures, features)
+	version, err := LibraryVersion()
+	require.NoError(t, err)
+	assert.Equal(t, wantVersion, version)
+
+	// sampleLossy was validated but never decoded.
+	decoded, err = OpenBytes(sampleLossy)
+	require.NoError(t, err)
+	_, err = decoded.Image()
+	assert.ErrorContains(t, err, "replay: no recorded decode for input sha256:")
+
+	_, err = (&Replay{}).SupportedFeatures()
+	assert.ErrorContains(t, err, "no recorded supported features")
+	_, err = (&Replay{}).Version()
+	assert.ErrorContains(t, err, "no recorded library version")
+}
+
+func TestUseFixture(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	data := mustFixtureData(t, FixtureAnimated)
+
+	t.Setenv(RecordEnv, "1")
+	stop, err := UseFixture(fixture)
+	require.NoError(t, err)
+	recorded := ValidateWebp(data)
+	require.NoError(t, stop())
+	assert.Nil(t, currentBackend())
+
+	t.Setenv(RecordEnv, "")
+	stop, err = UseFixture(fixture)
+	require.NoError(t, err)
+	assert.IsType(t, &Replay{}, currentBackend())
+	assert.Equal(t, recorded, ValidateWebp(data))
+	require.NoError(t, stop())
+
+	_, err = UseFixture(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read fixture")
+}
+
+func TestLoadReplayOldVersion(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	require.NoError(t, os.WriteFile(fixture, []byte(`{"version": 1, "entries": {}}`), 0o644))
+
+	_, err := LoadReplay(fixture)
+	assert.ErrorContains(t, err, "unsupported fixture version 1")
+	assert.ErrorContains(t, err, "re-record with "+RecordEnv+"=1")
+}
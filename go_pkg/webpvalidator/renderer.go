@@ -0,0 +1,156 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"webpValidatorTest/report"
+)
+
+// RendererCheck asks an external renderer, such as a headless browser,
+// whether it can display an image. The validator follows the
+// specification; a renderer check answers what a product is ultimately
+// judged by, for the files where the two might differ.
+//
+// An error means the renderer could not be asked, not that the file
+// failed to render; that is a Render with Rendered false.
+type RendererCheck interface {
+	CheckRender(ctx context.Context, data []byte) (report.Render, error)
+}
+
+// Borderline reports whether r deserves a second opinion from a renderer:
+// files that are valid despite structural errors, carry warnings, or
+// failed validation with metadata still parseable. Clean files and files
+// broken beyond parsing are not borderline.
+func Borderline(r report.Report) bool {
+	if r.Partial || (r.Valid && hasErrorFinding(r.Findings)) {
+		return true
+	}
+	for _, f := range r.Findings {
+		if f.Severity == report.SeverityWarning {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRender runs check on data and merges its verdict into r.
+func CheckRender(ctx context.Context, r *report.Report, data []byte, check RendererCheck) error {
+	render, err := check.CheckRender(ctx, data)
+	if err != nil {
+		return fmt.Errorf("renderer check failed: %w", err)
+	}
+	MergeRender(r, render)
+	return nil
+}
+
+// MergeRender adds a renderer verdict to r. A file the renderer cannot
+// display is invalid whatever the validator said, with an error finding;
+// a file it displays despite failing validation, or at other dimensions,
+// gets a warning.
+func MergeRender(r *report.Report, render report.Render) {
+	r.Renders = append(r.Renders, render)
+
+	var finding report.Finding
+	switch {
+	case !render.Rendered:
+		r.Valid = false
+		finding = report.Finding{
+			Severity: report.SeverityError,
+			Message:  fmt.Sprintf("%s failed to render the image", render.Renderer),
+		}
+		if render.Message != "" {
+			finding.Message += ": " + render.Message
+		}
+	case !r.Valid:
+		finding = report.Finding{
+			Severity: report.SeverityWarning,
+			Message:  fmt.Sprintf("%s renders the image despite the validation failure", render.Renderer),
+		}
+	case render.Width != r.Info.Width || render.Height != r.Info.Height:
+		finding = report.Finding{
+			Severity: report.SeverityWarning,
+			Message: fmt.Sprintf("%s rendered %dx%d, validator reported %dx%d",
+				render.Renderer, render.Width, render.Height, r.Info.Width, r.Info.Height),
+		}
+	default:
+		return
+	}
+	r.Findings = append(r.Findings, finding)
+}
+
+// maxRenderResponse bounds a renderer's answer, which is a short JSON
+// object.
+const maxRenderResponse = 64 << 10
+
+// HTTPRenderer is the reference RendererCheck, for a renderer running as
+// a sidecar service. It POSTs the file as image/webp to URL and expects a
+// 200 response with a JSON body:
+//
+//	{"rendered": true, "width": 640, "height": 480, "message": ""}
+//
+// Any other status is an error. Unknown fields are ignored.
+type HTTPRenderer struct {
+	URL string
+	// Name identifies the renderer in reports; the URL's host if empty.
+	Name string
+	// Client defaults to http.DefaultClient; bound slow renderers with its
+	// Timeout or the context.
+	Client *http.Client
+}
+
+func (h *HTTPRenderer) CheckRender(ctx context.Context, data []byte) (report.Render, error) {
+	name := h.Name
+	if name == "" {
+		if u, err := url.Parse(h.URL); err == nil && u.Host != "" {
+			name = u.Host
+		} else {
+			name = h.URL
+		}
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return report.Render{}, err
+	}
+	req.Header.Set("Content-Type", "image/webp")
+	resp, err := client.Do(req)
+	if err != nil {
+		return report.Render{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRenderResponse))
+	if err != nil {
+		return report.Render{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return report.Render{}, fmt.Errorf("%s: %s: %s", name, resp.Status, bytes.TrimSpace(body))
+	}
+
+	var answer struct {
+		Rendered bool   `json:"rendered"`
+		Width    uint32 `json:"width"`
+		Height   uint32 `json:"height"`
+		Message  string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &answer); err != nil {
+		return report.Render{}, fmt.Errorf("%s: invalid response: %w", name, err)
+	}
+	return report.Render{
+		Renderer: name,
+		Rendered: answer.Rendered,
+		Width:    answer.Width,
+		Height:   answer.Height,
+		Message:  answer.Message,
+	}, nil
+}
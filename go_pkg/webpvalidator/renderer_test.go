@@ -0,0 +1,90 @@
+package webpvalidator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"webpValidatorTest/report"
+)
+
+func TestBorderline(t *testing.T) {
+	warning := report.Finding{Severity: report.SeverityWarning, Message: "trailing data after riff container"}
+	failure := report.Finding{Severity: report.SeverityError, Message: "bad size"}
+
+	assert.False(t, Borderline(report.Report{Valid: true}))
+	assert.False(t, Borderline(report.Report{Findings: []report.Finding{failure}}), "broken beyond parsing")
+	assert.True(t, Borderline(report.Report{Valid: true, Findings: []report.Finding{warning}}))
+	assert.True(t, Borderline(report.Report{Valid: true, Findings: []report.Finding{failure}}))
+	assert.True(t, Borderline(report.Report{Partial: true, Findings: []report.Finding{failure}}))
+}
+
+func TestMergeRender(t *testing.T) {
+	valid := report.Report{Valid: true, Info: report.Info{Width: 4, Height: 3}}
+
+	r := valid
+	MergeRender(&r, report.Render{Renderer: "chromium", Rendered: true, Width: 4, Height: 3})
+	assert.True(t, r.Valid)
+	assert.Len(t, r.Renders, 1)
+	assert.Empty(t, r.Findings, "agreement adds no finding")
+
+	r = valid
+	MergeRender(&r, report.Render{Renderer: "chromium", Message: "decode error"})
+	assert.False(t, r.Valid)
+	assert.Equal(t, []report.Finding{{Severity: report.SeverityError, Message: "chromium failed to render the image: decode error"}}, r.Findings)
+
+	r = valid
+	MergeRender(&r, report.Render{Renderer: "chromium", Rendered: true, Width: 8, Height: 6})
+	assert.True(t, r.Valid)
+	assert.Equal(t, []report.Finding{{Severity: report.SeverityWarning, Message: "chromium rendered 8x6, validator reported 4x3"}}, r.Findings)
+
+	r = report.Report{}
+	MergeRender(&r, report.Render{Renderer: "chromium", Rendered: true})
+	assert.False(t, r.Valid)
+	assert.Equal(t, []report.Finding{{Severity: report.SeverityWarning, Message: "chromium renders the image despite the validation failure"}}, r.Findings)
+}
+
+// renderSidecar answers like a renderer that renders everything at 1x1,
+// and records the bodies it was sent.
+func renderSidecar(t *testing.T, received *[][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "image/webp", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		*received = append(*received, body)
+		json.NewEncoder(w).Encode(map[string]any{"rendered": true, "width": 1, "height": 1, "engine": "test"})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPRenderer(t *testing.T) {
+	var received [][]byte
+	server := renderSidecar(t, &received)
+
+	render, err := (&HTTPRenderer{URL: server.URL}).CheckRender(context.Background(), sampleLossy)
+	require.NoError(t, err)
+	assert.Equal(t, report.Render{Renderer: server.Listener.Addr().String(), Rendered: true, Width: 1, Height: 1}, render)
+	assert.Equal(t, [][]byte{sampleLossy}, received)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "browser crashed", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	_, err = (&HTTPRenderer{URL: failing.URL, Name: "chromium"}).CheckRender(context.Background(), sampleLossy)
+	assert.EqualError(t, err, "chromium: 503 Service Unavailable: browser crashed")
+
+	garbled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html>")
+	}))
+	defer garbled.Close()
+	_, err = (&HTTPRenderer{URL: garbled.URL, Name: "chromium"}).CheckRender(context.Background(), sampleLossy)
+	assert.ErrorContains(t, err, "chromium: invalid response")
+}
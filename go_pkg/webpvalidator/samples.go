@@ -0,0 +1,24 @@
+package webpvalidator
+
+import "encoding/base64"
+
+// Tiny well-formed WebP files, one per decoder code path, embedded so the
+// library can be exercised without touching disk.
+var (
+	// 1x1 lossy (VP8).
+	sampleLossy = mustDecodeSample("UklGRiIAAABXRUJQVlA4IBYAAAAwAQCdASoBAAEADsD+JaQAA3AAAAAA")
+	// 1x1 lossless (VP8L).
+	sampleLossless = mustDecodeSample("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	// 1x1 lossy with an ALPH chunk (VP8X).
+	sampleAlpha = mustDecodeSample("UklGRkoAAABXRUJQVlA4WAoAAAAQAAAAAAAAAAAAQUxQSAwAAAARBxAR/Q9ERP8DAABWUDggGAAAABQBAJ0BKgEAAQAAAP4AAA3AAP7mtQAAAA==")
+	// 1x1 single-frame animation (VP8X, ANIM, ANMF).
+	sampleAnimated = mustDecodeSample("UklGRlIAAABXRUJQVlA4WAoAAAASAAAAAAAAAAAAQU5JTQYAAAD/////AABBTk1GJgAAAAAAAAAAAAAAAAAAAGQAAABWUDhMDQAAAC8AAAAQBxAREYiI/gcA")
+)
+
+func mustDecodeSample(s string) []byte {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
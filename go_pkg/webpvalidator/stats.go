@@ -0,0 +1,155 @@
+package webpvalidator
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// FailureCode classifies why a validation failed.
+type FailureCode int
+
+const (
+	FailureOther     FailureCode = iota
+	FailureEmpty                 // no data
+	FailureTooLarge              // beyond the RIFF size limit or this platform
+	FailureTruncated             // shorter than the RIFF header declares
+	FailureFormat                // rejected by the decoder
+	FailureRead                  // the file could not be read
+	FailureChanged               // ErrFileChangedDuringValidation
+	FailureLibrary               // the native library could not be loaded
+
+	// FailureCodeCount is the number of failure codes, for indexing
+	// StatsSnapshot.FailuresByCode.
+	FailureCodeCount
+)
+
+var failureCodeNames = [FailureCodeCount]string{
+	"other", "empty", "too_large", "truncated", "format", "read", "changed", "library",
+}
+
+func (c FailureCode) String() string {
+	if c < 0 || c >= FailureCodeCount {
+		return "unknown"
+	}
+	return failureCodeNames[c]
+}
+
+// StatsSnapshot is a point-in-time copy of the process-wide counters.
+// Counters only grow; subtract two snapshots for a rate. Individual
+// counters are read atomically but not all at the same instant, so a
+// snapshot taken mid-validation may be off by one between fields.
+type StatsSnapshot struct {
+	// Validations counts ValidateWebp calls, including those made by the
+	// Pool and the CLI commands, and ValidateWebpFile, ValidateWebpReader
+	// and Open calls. Each call counts once with its final outcome, even
+	// when the input cannot be read or the file changes underneath it.
+	Validations uint64
+	// Bytes is the total input size of those validations.
+	Bytes uint64
+	// Failures counts failed validations; FailuresByCode breaks them down
+	// by FailureCode.
+	Failures       uint64
+	FailuresByCode [FailureCodeCount]uint64
+	Inspections    uint64
+	// Decodes counts pixel decodes by a Decoded handle, of every frame or,
+	// for Image and what is derived from it, of the first frame only.
+	Decodes uint64
+	// CacheHits and CacheMisses count lookups of results cached on a
+	// Decoded handle: decoded frames, thumbnails, placeholder hashes and
+	// dominant colors.
+	CacheHits   uint64
+	CacheMisses uint64
+	// VerdictCacheHits and VerdictCacheMisses count CachedBackend lookups
+	// in a VerdictCache, shared between processes or not.
+	VerdictCacheHits   uint64
+	VerdictCacheMisses uint64
+}
+
+var counters struct {
+	validations    atomic.Uint64
+	bytes          atomic.Uint64
+	failures       atomic.Uint64
+	failuresByCode [FailureCodeCount]atomic.Uint64
+	inspections    atomic.Uint64
+	decodes        atomic.Uint64
+	cacheHits      atomic.Uint64
+	cacheMisses    atomic.Uint64
+	verdictHits    atomic.Uint64
+	verdictMisses  atomic.Uint64
+}
+
+// Stats returns the current counters. It does not allocate or lock, so it
+// is cheap enough to call from any telemetry loop.
+func Stats() StatsSnapshot {
+	s := StatsSnapshot{
+		Validations: counters.validations.Load(),
+		Bytes:       counters.bytes.Load(),
+		Failures:    counters.failures.Load(),
+		Inspections: counters.inspections.Load(),
+		Decodes:     counters.decodes.Load(),
+		CacheHits:   counters.cacheHits.Load(),
+		CacheMisses: counters.cacheMisses.Load(),
+
+		VerdictCacheHits:   counters.verdictHits.Load(),
+		VerdictCacheMisses: counters.verdictMisses.Load(),
+	}
+	for i := range s.FailuresByCode {
+		s.FailuresByCode[i] = counters.failuresByCode[i].Load()
+	}
+	return s
+}
+
+// recordValidation counts one validation and its outcome.
+func recordValidation(info WebpInfo, size int) {
+	counters.validations.Add(1)
+	counters.bytes.Add(uint64(size))
+	if !info.IsValid {
+		recordFailure(classifyFailure(info))
+	}
+}
+
+func recordFailure(code FailureCode) {
+	counters.failures.Add(1)
+	counters.failuresByCode[code].Add(1)
+}
+
+func recordCacheLookup(hit bool) {
+	if hit {
+		counters.cacheHits.Add(1)
+	} else {
+		counters.cacheMisses.Add(1)
+	}
+}
+
+func recordVerdictCacheLookup(hit bool) {
+	if hit {
+		counters.verdictHits.Add(1)
+	} else {
+		counters.verdictMisses.Add(1)
+	}
+}
+
+// classifyFailure maps a failed result to a FailureCode. The native
+// library reports failures as messages, so this matches their stable
+// prefixes.
+func classifyFailure(info WebpInfo) FailureCode {
+	if errors.Is(info.Err, ErrFileChangedDuringValidation) {
+		return FailureChanged
+	}
+	switch msg := info.Error; {
+	case msg == "data is empty":
+		return FailureEmpty
+	case strings.HasPrefix(msg, "failed to read"):
+		return FailureRead
+	case strings.Contains(msg, "exceeds riff size limit"), strings.Contains(msg, "too large for this platform"):
+		return FailureTooLarge
+	case strings.HasPrefix(msg, "webp file is truncated"):
+		return FailureTruncated
+	case strings.HasPrefix(msg, "webp format validation failed"):
+		return FailureFormat
+	case strings.HasPrefix(msg, "failed to load native library"):
+		return FailureLibrary
+	}
+	return FailureOther
+}
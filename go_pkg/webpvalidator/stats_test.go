@@ -0,0 +1,109 @@
+package webpvalidator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsCounters(t *testing.T) {
+	fake, err := os.Stat(mustFixturePath(t, FixtureNotWebp))
+	require.NoError(t, err)
+	before := Stats()
+
+	ValidateWebp(sampleLossy)
+	ValidateWebp(nil)
+	ValidateWebpFile(mustFixturePath(t, FixtureNotWebp))
+	ValidateWebpFile(filepath.Join(t.TempDir(), "nonexistent.webp"))
+	InspectWebp(sampleLossy)
+
+	decoded, err := OpenBytes(sampleAlpha)
+	require.NoError(t, err)
+	_, err = decoded.Frames()
+	require.NoError(t, err)
+	_, err = decoded.Frames()
+	require.NoError(t, err)
+
+	after := Stats()
+	assert.Equal(t, uint64(5), after.Validations-before.Validations, "a file that cannot be read is a failed validation")
+	assert.Equal(t, uint64(len(sampleLossy)+len(sampleAlpha))+uint64(fake.Size()), after.Bytes-before.Bytes)
+	assert.Equal(t, uint64(3), after.Failures-before.Failures)
+	assert.Equal(t, uint64(1), after.FailuresByCode[FailureEmpty]-before.FailuresByCode[FailureEmpty])
+	assert.Equal(t, uint64(1), after.FailuresByCode[FailureFormat]-before.FailuresByCode[FailureFormat])
+	assert.Equal(t, uint64(1), after.FailuresByCode[FailureRead]-before.FailuresByCode[FailureRead])
+	assert.Equal(t, uint64(1), after.Inspections-before.Inspections)
+	assert.Equal(t, uint64(1), after.Decodes-before.Decodes)
+	assert.Equal(t, uint64(1), after.CacheHits-before.CacheHits)
+	assert.Equal(t, uint64(1), after.CacheMisses-before.CacheMisses)
+}
+
+func TestStatsFileChangedDuringValidation(t *testing.T) {
+	data := mustFixtureData(t, FixtureStatic)
+	path := filepath.Join(t.TempDir(), "upload.webp")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	previous := SetBackend(hookBackend{func() {
+		require.NoError(t, os.WriteFile(path, append(data, "more"...), 0o644))
+	}})
+	defer SetBackend(previous)
+
+	before := Stats()
+	info := ValidateWebpFile(path)
+	require.ErrorIs(t, info.Err, ErrFileChangedDuringValidation)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	_, err := Open(path)
+	require.ErrorIs(t, err, ErrFileChangedDuringValidation)
+	after := Stats()
+
+	// The change replaces the validation's result instead of adding a
+	// second outcome to it.
+	assert.Equal(t, uint64(2), after.Validations-before.Validations)
+	assert.Equal(t, uint64(2)*uint64(len(data)), after.Bytes-before.Bytes)
+	assert.Equal(t, uint64(2), after.Failures-before.Failures)
+	assert.Equal(t, uint64(2), after.FailuresByCode[FailureChanged]-before.FailuresByCode[FailureChanged])
+}
+
+func TestStatsReadFailures(t *testing.T) {
+	before := Stats()
+	_, _, err := ReadWebpFile(filepath.Join(t.TempDir(), "nonexistent.webp"), nil)
+	require.Error(t, err)
+	assert.Equal(t, before, Stats(), "reading alone is not a validation")
+
+	info := ValidateWebpReader(iotest.ErrReader(errors.New("disk on fire")))
+	require.False(t, info.IsValid)
+	after := Stats()
+	assert.Equal(t, uint64(1), after.Validations-before.Validations)
+	assert.Equal(t, uint64(1), after.Failures-before.Failures)
+	assert.Equal(t, uint64(1), after.FailuresByCode[FailureRead]-before.FailuresByCode[FailureRead])
+}
+
+func TestStatsDoesNotAllocate(t *testing.T) {
+	var s StatsSnapshot
+	allocs := testing.AllocsPerRun(100, func() { s = Stats() })
+	assert.Zero(t, allocs)
+	_ = s
+}
+
+func TestClassifyFailure(t *testing.T) {
+	for msg, want := range map[string]FailureCode{
+		"data is empty": FailureEmpty,
+		"failed to read file: open x: no such file or directory":  FailureRead,
+		"webp file exceeds riff size limit: 5000000000 bytes":     FailureTooLarge,
+		"webp file is truncated: riff header declares 10 bytes":   FailureTruncated,
+		"webp format validation failed: ChunkHeaderInvalid":       FailureFormat,
+		"failed to load native library libwebp_validator.so: ...": FailureLibrary,
+		"something new": FailureOther,
+	} {
+		assert.Equal(t, want, classifyFailure(WebpInfo{Error: msg}), msg)
+	}
+
+	err := fmt.Errorf("%w: file was replaced", ErrFileChangedDuringValidation)
+	assert.Equal(t, FailureChanged, classifyFailure(WebpInfo{Error: err.Error(), Err: err}))
+	assert.Equal(t, "changed", FailureChanged.String())
+	assert.Equal(t, "unknown", FailureCodeCount.String())
+}
@@ -0,0 +1,36 @@
+package webpvalidator
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"webpValidatorTest/webptmpl"
+)
+
+// TemplateFuncs returns html/template functions describing the images
+// below root, which templates name by slash-separated relative path:
+//
+//	funcs := TemplateFuncs("public/img")
+//	tmpl := template.Must(template.New("page").Funcs(funcs.FuncMap()).ParseFiles("page.html"))
+//
+// Each image is validated and decoded once and then served from the
+// cache; call Forget after replacing a file.
+func TemplateFuncs(root string) *webptmpl.Funcs {
+	return webptmpl.New(func(name string) (webptmpl.Image, error) {
+		if !fs.ValidPath(name) {
+			return webptmpl.Image{}, fmt.Errorf("invalid image name %q", name)
+		}
+		decoded, err := Open(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			return webptmpl.Image{}, fmt.Errorf("%s: %w", name, err)
+		}
+
+		info := decoded.Info()
+		img := webptmpl.Image{Width: info.Width, Height: info.Height}
+		// Dimensions are still useful without a placeholder, for images
+		// too large to decode within MaxDecodedBytes.
+		img.Placeholder, _ = decoded.PlaceholderHash()
+		return img, nil
+	})
+}
@@ -0,0 +1,35 @@
+package webpvalidator
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	funcs := TemplateFuncs(filepath.Dir(mustFixturePath(t, FixtureStatic)))
+	tmpl := template.Must(template.New("page").Funcs(funcs.FuncMap()).Parse(
+		`<img {{webpDims "static.webp"}}>|{{webpAspect "static.webp"}}|{{webpPlaceholder "animated.webp"}}`))
+
+	var out strings.Builder
+	require.NoError(t, tmpl.Execute(&out, nil))
+	parts := strings.Split(out.String(), "|")
+	require.Len(t, parts, 3)
+
+	decoded, err := Open(mustFixturePath(t, FixtureStatic))
+	require.NoError(t, err)
+	info := decoded.Info()
+	assert.Equal(t, fmt.Sprintf(`<img width="%d" height="%d">`, info.Width, info.Height), parts[0])
+	assert.Contains(t, parts[1], " / ")
+	assert.Len(t, parts[2], 28)
+
+	_, err = funcs.Dims(FixtureNotWebp)
+	assert.ErrorContains(t, err, FixtureNotWebp+": webp format validation failed")
+	_, err = funcs.Dims("../" + FixtureStatic)
+	assert.ErrorContains(t, err, "invalid image name")
+}
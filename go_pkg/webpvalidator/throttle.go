@@ -0,0 +1,69 @@
+package webpvalidator
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// throttleChunkSize caps a single throttled read so the rate stays smooth
+// instead of bursting one large read and then sleeping.
+const throttleChunkSize = 64 << 10
+
+// Throttle limits the combined read rate of every reader wrapped by it,
+// so background scans do not saturate disks serving other traffic.
+// A nil *Throttle does not limit anything.
+type Throttle struct {
+	bytesPerSec int64
+
+	mu sync.Mutex
+	// next is when the bytes reserved so far will have been paid for.
+	next time.Time
+}
+
+// NewThrottle returns a throttle for bytesPerSec, or nil if bytesPerSec
+// is not positive.
+func NewThrottle(bytesPerSec int64) *Throttle {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &Throttle{bytesPerSec: bytesPerSec}
+}
+
+// wait blocks until n more bytes may be read.
+func (t *Throttle) wait(n int) {
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(int64(n) * int64(time.Second) / t.bytesPerSec))
+	until := t.next
+	t.mu.Unlock()
+
+	time.Sleep(time.Until(until))
+}
+
+// Reader wraps r so its reads count against the throttle.
+func (t *Throttle) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &throttledReader{r: r, throttle: t}
+}
+
+type throttledReader struct {
+	r        io.Reader
+	throttle *Throttle
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkSize {
+		p = p[:throttleChunkSize]
+	}
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		tr.throttle.wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,33 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIOThrottleLimitsRate(t *testing.T) {
+	const rate = 1 << 20
+	throttle := NewThrottle(rate)
+	data := make([]byte, 200<<10)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, throttle.Reader(bytes.NewReader(data)))
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	expected := time.Duration(len(data)) * time.Second / rate
+	assert.GreaterOrEqual(t, elapsed, expected*8/10, "reads should be throttled to ~%v", expected)
+}
+
+func TestIOThrottleNilIsUnlimited(t *testing.T) {
+	assert.Nil(t, NewThrottle(0))
+
+	r := bytes.NewReader(nil)
+	assert.Same(t, r, NewThrottle(0).Reader(r))
+}
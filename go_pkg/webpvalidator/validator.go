@@ -0,0 +1,209 @@
+package webpvalidator
+
+/*
+#include "native.h"
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+type WebpInfo struct {
+	IsValid bool
+	Width   uint32
+	Height  uint32
+	// Deprecated: use Features.Has(FeatureAlpha).
+	HasAlpha bool
+	// Deprecated: use Features.Has(FeatureAnimation).
+	IsAnimated bool
+	NumFrames  uint32
+	// Features are the capabilities the file uses, as reported by the
+	// native library; bits a newer library sets are kept as they are (see
+	// Features.Unknown).
+	Features Features
+	// Partial reports that the file failed validation and the fields
+	// above hold whatever metadata could still be parsed.
+	Partial bool
+	Error   string
+	// Err is the error behind Error when there is one to match with
+	// errors.Is, such as ErrFileChangedDuringValidation.
+	Err error `json:"-"`
+}
+
+func ValidateWebp(data []byte) WebpInfo {
+	info := validate(data)
+	recordValidation(info, len(data))
+	return info
+}
+
+// validate is ValidateWebp without counting in Stats, for callers that
+// count the outcome themselves.
+func validate(data []byte) WebpInfo {
+	if b := currentBackend(); b != nil {
+		return b.Validate(data)
+	}
+	return validateNative(data)
+}
+
+func InspectWebp(data []byte) WebpInspection {
+	counters.inspections.Add(1)
+	if b := currentBackend(); b != nil {
+		return b.Inspect(data)
+	}
+	return inspectNative(data)
+}
+
+func validateNative(data []byte) WebpInfo {
+	if len(data) == 0 {
+		return WebpInfo{
+			IsValid: false,
+			Error:   "data is empty",
+		}
+	}
+	if _, err := LoadNativeLibrary(); err != nil {
+		return WebpInfo{
+			IsValid: false,
+			Error:   err.Error(),
+		}
+	}
+
+	// Pass the Go buffer directly instead of copying it with C.CBytes:
+	// the native side does not retain the pointer, and copying doubles
+	// peak memory for multi-gigabyte files.
+	result := C.webp_native_validate((*C.uint8_t)(unsafe.Pointer(&data[0])), C.size_t(len(data)))
+
+	info := WebpInfo{
+		IsValid:    bool(result.is_valid),
+		Width:      uint32(result.width),
+		Height:     uint32(result.height),
+		HasAlpha:   bool(result.has_alpha),
+		IsAnimated: bool(result.is_animated),
+		NumFrames:  uint32(result.num_frames),
+		Features:   Features(result.features),
+		Partial:    bool(result.is_partial),
+	}
+
+	if result.error_message != nil {
+		info.Error = C.GoString(result.error_message)
+		C.webp_native_free_error_message(result.error_message)
+	}
+
+	return info
+}
+
+func inspectNative(data []byte) WebpInspection {
+	if len(data) == 0 {
+		return WebpInspection{
+			Findings: []WebpFinding{{Message: "data is empty"}},
+		}
+	}
+	if _, err := LoadNativeLibrary(); err != nil {
+		return WebpInspection{
+			Findings: []WebpFinding{{Message: err.Error()}},
+		}
+	}
+
+	result := C.webp_native_inspect((*C.uint8_t)(unsafe.Pointer(&data[0])), C.size_t(len(data)))
+	defer C.webp_native_free_inspection(result)
+
+	var inspection WebpInspection
+	if result.num_chunks > 0 {
+		for _, c := range unsafe.Slice(result.chunks, result.num_chunks) {
+			inspection.Chunks = append(inspection.Chunks, WebpChunk{
+				FourCC: C.GoStringN((*C.char)(unsafe.Pointer(&c.fourcc[0])), 4),
+				Offset: uint64(c.offset),
+				Size:   uint32(c.size),
+				Depth:  uint32(c.depth),
+			})
+		}
+	}
+	if result.num_findings > 0 {
+		for _, f := range unsafe.Slice(result.findings, result.num_findings) {
+			inspection.Findings = append(inspection.Findings, WebpFinding{
+				Offset:  uint64(f.offset),
+				Length:  uint64(f.length),
+				Warning: bool(f.is_warning),
+				Message: C.GoString(f.message),
+			})
+		}
+	}
+
+	return inspection
+}
+
+// decodeNative decodes data into pixels and durations, which the caller
+// sizes from a successful validation (see decode_webp_ffi).
+func decodeNative(data, pixels []byte, durations []uint32) error {
+	if len(data) == 0 || len(pixels) == 0 || len(durations) == 0 {
+		return errors.New("decode buffers are empty")
+	}
+	if _, err := LoadNativeLibrary(); err != nil {
+		return err
+	}
+
+	cErr := C.webp_native_decode(
+		(*C.uint8_t)(unsafe.Pointer(&data[0])), C.size_t(len(data)),
+		(*C.uint8_t)(unsafe.Pointer(&pixels[0])), C.size_t(len(pixels)),
+		(*C.uint32_t)(unsafe.Pointer(&durations[0])), C.size_t(len(durations)))
+	if cErr != nil {
+		defer C.webp_native_free_error_message(cErr)
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// SupportedFeatures returns every Features bit the loaded native library
+// can report, including bits newer than these bindings. A bit outside it
+// is never set, as opposed to a known bit that is unset for a given file.
+func SupportedFeatures() (Features, error) {
+	if b := currentBackend(); b != nil {
+		return b.SupportedFeatures()
+	}
+	return supportedFeaturesNative()
+}
+
+// LibraryVersion returns the version of the loaded native library.
+func LibraryVersion() (string, error) {
+	if b := currentBackend(); b != nil {
+		return b.Version()
+	}
+	return versionNative()
+}
+
+func versionNative() (string, error) {
+	if _, err := LoadNativeLibrary(); err != nil {
+		return "", err
+	}
+	return C.GoString(C.webp_native_version()), nil
+}
+
+// decodeWebp is decodeNative routed through the active backend.
+func decodeWebp(data, pixels []byte, durations []uint32) error {
+	if b := currentBackend(); b != nil {
+		return b.Decode(data, pixels, durations)
+	}
+	return decodeNative(data, pixels, durations)
+}
+
+func supportedFeaturesNative() (Features, error) {
+	if _, err := LoadNativeLibrary(); err != nil {
+		return 0, err
+	}
+	return Features(C.webp_native_supported_features()), nil
+}
+
+// openNativeLibrary loads the library at path, see webp_native_open.
+func openNativeLibrary(path string) error {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var cErr *C.char
+	if C.webp_native_open(cPath, &cErr) == 0 {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build linux
+
+package webpvalidator
+
+// #cgo LDFLAGS: -ldl
+import "C"
+
+// nativeLibraryName is the file name searched for by the loader.
+const nativeLibraryName = "libwebp_validator.so"
@@ -0,0 +1,302 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "golang.org/x/image/webp"
+)
+
+// ValidateWebpByStdLib validates WebP using Go standard library.
+// Note: Go stdlib's webp package does not support animated WebP.
+func ValidateWebpByStdLib(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	_, _, err = image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid image file: %w", err)
+	}
+
+	return nil
+}
+
+func TestValidateStaticWebp(t *testing.T) {
+	data := mustFixtureData(t, FixtureStatic)
+	info := ValidateWebp(data)
+
+	assert.True(t, info.IsValid, "static webp should be valid")
+	assert.False(t, info.IsAnimated, "static webp should not be animated")
+	assert.Equal(t, uint32(0), info.NumFrames, "static webp should have 0 frames")
+	assert.Greater(t, info.Width, uint32(0), "width should be greater than 0")
+	assert.Greater(t, info.Height, uint32(0), "height should be greater than 0")
+
+	t.Logf("static webp validated successfully: %dx%d", info.Width, info.Height)
+}
+
+func TestValidateDynamicWebp(t *testing.T) {
+	data := mustFixtureData(t, FixtureAnimated)
+	info := ValidateWebp(data)
+
+	assert.True(t, info.IsValid, "dynamic webp should be valid")
+	assert.True(t, info.IsAnimated, "dynamic webp should be animated")
+	assert.Greater(t, info.NumFrames, uint32(1), "dynamic webp should have multiple frames")
+	assert.Greater(t, info.Width, uint32(0), "width should be greater than 0")
+	assert.Greater(t, info.Height, uint32(0), "height should be greater than 0")
+
+	t.Logf("dynamic webp validated successfully: %dx%d, %d frames", info.Width, info.Height, info.NumFrames)
+}
+
+func TestValidateFakeWebp(t *testing.T) {
+	data := mustFixtureData(t, FixtureNotWebp)
+	info := ValidateWebp(data)
+
+	assert.False(t, info.IsValid, "fake webp should be invalid")
+	assert.NotEmpty(t, info.Error, "fake webp should have error message")
+	assert.Contains(t, info.Error, "webp format validation failed", "error should indicate validation failure")
+	assert.False(t, info.Partial, "fake webp should have no partial metadata")
+
+	t.Logf("fake webp correctly rejected: %s", info.Error)
+}
+
+func TestValidateTruncatedWebpPartial(t *testing.T) {
+	data := mustFixtureData(t, FixtureAnimated)
+	full := ValidateWebp(data)
+	require.True(t, full.IsValid, "dynamic webp should be valid")
+
+	info := ValidateWebp(data[:len(data)/2])
+	assert.False(t, info.IsValid, "truncated webp should be invalid")
+	assert.True(t, info.Partial, "truncated webp should carry partial metadata")
+	assert.Equal(t, full.Width, info.Width, "width should be recovered from VP8X")
+	assert.Equal(t, full.Height, info.Height, "height should be recovered from VP8X")
+	assert.True(t, info.IsAnimated, "animation flag should be recovered from VP8X")
+	assert.Less(t, info.NumFrames, full.NumFrames, "only frames seen so far should be counted")
+	assert.Greater(t, info.NumFrames, uint32(0), "frames seen so far should be counted")
+
+	t.Logf("truncated webp partial metadata: %dx%d, %d frames: %s", info.Width, info.Height, info.NumFrames, info.Error)
+}
+
+func TestValidateNonexistentFile(t *testing.T) {
+	// For nonexistent file, we can't read it, so we can't pass bytes.
+	// The Rust side no longer handles file opening, so this test case changes.
+	// We should test that ValidateWebp handles empty or invalid data gracefully if we want.
+	// But strictly speaking, the responsibility of opening the file is now on the caller (Go side).
+	// So we can test what happens if we pass empty bytes or garbage bytes.
+
+	// Let's test empty bytes
+	info := ValidateWebp([]byte{})
+	assert.False(t, info.IsValid, "empty data should be invalid")
+	assert.NotEmpty(t, info.Error, "empty data should have error message")
+
+	t.Logf("empty data correctly handled: %s", info.Error)
+}
+
+func TestValidateWebpFile(t *testing.T) {
+	info := ValidateWebpFile(mustFixturePath(t, FixtureAnimated))
+	assert.True(t, info.IsValid, "dynamic webp should be valid")
+	assert.True(t, info.IsAnimated, "dynamic webp should be animated")
+
+	info = ValidateWebpFile(filepath.Join(t.TempDir(), "nonexistent.webp"))
+	assert.False(t, info.IsValid, "nonexistent file should be invalid")
+	assert.Contains(t, info.Error, "failed to read file", "error should indicate read failure")
+}
+
+func TestValidateWebpReader(t *testing.T) {
+	f, err := os.Open(mustFixturePath(t, FixtureStatic))
+	require.NoError(t, err)
+	defer f.Close()
+
+	info := ValidateWebpReader(f)
+	assert.True(t, info.IsValid, "static webp should be valid")
+	assert.False(t, info.IsAnimated, "static webp should not be animated")
+}
+
+// hookBackend runs hook before each native validation, to simulate a
+// writer touching the file mid-validation.
+type hookBackend struct{ hook func() }
+
+func (b hookBackend) Validate(data []byte) WebpInfo {
+	b.hook()
+	return NativeBackend.Validate(data)
+}
+
+func (b hookBackend) Inspect(data []byte) WebpInspection {
+	return NativeBackend.Inspect(data)
+}
+
+func (b hookBackend) Decode(data, pixels []byte, durations []uint32) error {
+	return NativeBackend.Decode(data, pixels, durations)
+}
+
+func (b hookBackend) SupportedFeatures() (Features, error) {
+	return NativeBackend.SupportedFeatures()
+}
+
+func (b hookBackend) Version() (string, error) { return NativeBackend.Version() }
+
+func TestValidateWebpFileChanged(t *testing.T) {
+	data := mustFixtureData(t, FixtureStatic)
+	path := filepath.Join(t.TempDir(), "upload.webp")
+
+	for name, hook := range map[string]func(){
+		"appended": func() {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+			require.NoError(t, err)
+			_, err = f.Write([]byte("more"))
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+		},
+		"replaced": func() {
+			replacement := path + ".tmp"
+			require.NoError(t, os.WriteFile(replacement, data, 0o644))
+			require.NoError(t, os.Rename(replacement, path))
+		},
+		"removed": func() {
+			require.NoError(t, os.Remove(path))
+		},
+	} {
+		require.NoError(t, os.WriteFile(path, data, 0o644), name)
+		previous := SetBackend(hookBackend{hook})
+		info := ValidateWebpFile(path)
+		SetBackend(previous)
+
+		assert.False(t, info.IsValid, name)
+		assert.ErrorIs(t, info.Err, ErrFileChangedDuringValidation, name)
+		assert.Contains(t, info.Error, "file changed during validation", name)
+	}
+
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	info := ValidateWebpFile(path)
+	assert.True(t, info.IsValid)
+	assert.NoError(t, info.Err)
+
+	_, err := Open(path)
+	require.NoError(t, err)
+	previous := SetBackend(hookBackend{func() { require.NoError(t, os.Truncate(path, 10)) }})
+	defer SetBackend(previous)
+	_, err = Open(path)
+	assert.ErrorIs(t, err, ErrFileChangedDuringValidation)
+}
+
+func TestValidateOversizedSparseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oversized.webp")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	// Sparse: no data blocks are allocated, so this is cheap on disk.
+	require.NoError(t, f.Truncate(MaxWebpFileSize+1))
+	require.NoError(t, f.Close())
+
+	info := ValidateWebpFile(path)
+	assert.False(t, info.IsValid, "file above the riff limit should be invalid")
+	assert.Contains(t, info.Error, "exceeds riff size limit", "error should name the riff limit")
+}
+
+// TestValidateLargeSparseFile validates a file above 2GB to catch 32-bit
+// size truncation. It needs ~2.3GB of memory, so it only runs when
+// WEBP_VALIDATOR_LARGE_TESTS is set.
+func TestValidateLargeSparseFile(t *testing.T) {
+	if os.Getenv("WEBP_VALIDATOR_LARGE_TESTS") == "" {
+		t.Skip("set WEBP_VALIDATOR_LARGE_TESTS=1 to run")
+	}
+
+	data := mustFixtureData(t, FixtureStatic)
+
+	// Append a single unknown chunk covering the rest of a 2.3GB file and
+	// patch the riff size so the container stays well-formed.
+	const total = 2_300_000_000
+	junkSize := total - len(data) - 8
+	riffSize := uint32(total - 8)
+	binary.LittleEndian.PutUint32(data[4:8], riffSize)
+	data = append(data, 'J', 'U', 'N', 'K', 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[len(data)-4:], uint32(junkSize))
+
+	path := filepath.Join(t.TempDir(), "large.webp")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	require.NoError(t, os.Truncate(path, total))
+
+	info := ValidateWebpFile(path)
+	require.True(t, info.IsValid, "large webp should be valid: %s", info.Error)
+	assert.Greater(t, info.Width, uint32(0), "width should be greater than 0")
+	assert.Greater(t, info.Height, uint32(0), "height should be greater than 0")
+}
+
+// TestCompareWithStdLib demonstrates that Go stdlib cannot handle animated WebP.
+func TestCompareWithStdLib(t *testing.T) {
+	dynamicWebpPath := mustFixturePath(t, FixtureAnimated)
+
+	// Validate using Rust library
+	data, err := os.ReadFile(dynamicWebpPath)
+	require.NoError(t, err)
+	rustResult := ValidateWebp(data)
+	require.True(t, rustResult.IsValid, "rust library should validate dynamic webp")
+	require.True(t, rustResult.IsAnimated, "rust library should detect animation")
+	require.Greater(t, rustResult.NumFrames, uint32(1), "rust library should detect multiple frames")
+	t.Logf("rust library result: valid=%v, animated=%v, frames=%d",
+		rustResult.IsValid, rustResult.IsAnimated, rustResult.NumFrames)
+
+	// Validate using Go standard library
+	stdlibErr := ValidateWebpByStdLib(dynamicWebpPath)
+	assert.Error(t, stdlibErr, "golang stdlib should fail on animated webp")
+	if stdlibErr != nil {
+		t.Logf("golang stdlib result: error (expected) - %v", stdlibErr)
+		t.Logf("this proves golang stdlib cannot handle animated webp files")
+	}
+
+	t.Log("Conclusion: Rust library has full WebP support including animation")
+	t.Log("Go stdlib lacks animated WebP support")
+}
+
+// BenchmarkValidateWebp measures performance of Rust library validation
+func BenchmarkValidateWebp(b *testing.B) {
+	data := mustFixtureData(b, FixtureStatic)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ValidateWebp(data)
+	}
+}
+
+func BenchmarkValidateWebpStdLib(b *testing.B) {
+	path := mustFixturePath(b, FixtureStatic)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ValidateWebpByStdLib(path)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	before := Stats()
+	require.NoError(t, Warmup(context.Background()))
+	assert.Equal(t, before, Stats(), "warmup is not counted")
+
+	// Warmup exercises the native library even when another backend is set.
+	previous := SetBackend(failingBackend{})
+	require.NoError(t, Warmup(context.Background()))
+	SetBackend(previous)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, Warmup(ctx), context.Canceled)
+}
+
+func TestEmbeddedSamples(t *testing.T) {
+	for _, sample := range warmupSamples() {
+		info := ValidateWebp(sample.data)
+		assert.Equal(t, sample.valid, info.IsValid, "%s: %s", sample.name, info.Error)
+		assert.Equal(t, sample.animated, info.IsAnimated, sample.name)
+		assert.Equal(t, uint32(1), info.Width, sample.name)
+		assert.Equal(t, uint32(1), info.Height, sample.name)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package webpvalidator
+
+// nativeLibraryName is the file name searched for by the loader.
+const nativeLibraryName = "webp_validator.dll"
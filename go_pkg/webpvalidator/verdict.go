@@ -0,0 +1,92 @@
+package webpvalidator
+
+import "webpValidatorTest/report"
+
+// Verdict validates and inspects data and combines the decoder result and
+// the container structure in one report, the one the verdict command
+// prints for the file at path.
+func Verdict(path string, data []byte) report.Report {
+	return buildVerdict(path, data, ValidateWebp(data), InspectWebp(data))
+}
+
+// buildVerdict is Verdict for results obtained elsewhere.
+func buildVerdict(path string, data []byte, info WebpInfo, inspection WebpInspection) report.Report {
+	v := report.Report{
+		Version: report.Version,
+		Path:    path,
+		Size:    len(data),
+		Valid:   info.IsValid,
+		Partial: info.Partial,
+		Error:   info.Error,
+		Info: report.Info{
+			Width:      info.Width,
+			Height:     info.Height,
+			HasAlpha:   info.HasAlpha,
+			IsAnimated: info.IsAnimated,
+			NumFrames:  info.NumFrames,
+		},
+		Chunks:   []report.Chunk{},
+		Frames:   []report.Frame{},
+		Findings: []report.Finding{},
+		Renders:  []report.Render{},
+	}
+
+	for _, c := range inspection.Chunks {
+		v.Chunks = append(v.Chunks, report.Chunk{
+			FourCC:        c.FourCC,
+			Offset:        c.Offset,
+			Length:        c.Length(),
+			PayloadOffset: c.PayloadOffset(),
+			PayloadLength: c.Size,
+			Depth:         c.Depth,
+		})
+	}
+
+	for i, f := range inspection.Frames(data) {
+		v.Frames = append(v.Frames, report.Frame{
+			Index:               i,
+			Offset:              f.Chunk.Offset,
+			X:                   f.X,
+			Y:                   f.Y,
+			Width:               f.Width,
+			Height:              f.Height,
+			DurationMs:          f.Duration,
+			Blend:               f.Blend,
+			DisposeToBackground: f.DisposeToBackground,
+		})
+	}
+
+	for _, f := range inspection.Findings {
+		severity := report.SeverityError
+		if f.Warning {
+			severity = report.SeverityWarning
+		}
+		offset, length := f.Offset, f.Length
+		v.Findings = append(v.Findings, report.Finding{
+			Severity: severity,
+			Message:  f.Message,
+			Offset:   &offset,
+			Length:   &length,
+		})
+	}
+
+	// The structural walk usually pinpoints why the decoder failed; only
+	// report the decoder error on its own when it found nothing.
+	if !info.IsValid && !hasErrorFinding(v.Findings) {
+		v.Findings = append(v.Findings, report.Finding{
+			Severity: report.SeverityError,
+			Message:  info.Error,
+		})
+	}
+
+	return v
+}
+
+func hasErrorFinding(findings []report.Finding) bool {
+	for _, f := range findings {
+		if f.Severity == report.SeverityError {
+			return true
+		}
+	}
+	return false
+}
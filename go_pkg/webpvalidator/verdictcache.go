@@ -0,0 +1,412 @@
+package webpvalidator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// VerdictCacheEnv names the environment variable that points the CLI at a
+// shared MmapCache file.
+const VerdictCacheEnv = "WEBP_VALIDATOR_CACHE"
+
+// VerdictCache stores validation results by a SHA-256 key, which
+// CachedBackend derives from the input and the library that validated it.
+// Implementations are best effort: Get may miss an entry that was Put, for
+// example after a collision evicted it, but must never return a result
+// stored under another key. They must be safe for concurrent use.
+type VerdictCache interface {
+	Get(key [sha256.Size]byte) (WebpInfo, bool)
+	Put(key [sha256.Size]byte, info WebpInfo)
+}
+
+// CachedBackend is a Backend that answers Validate from a VerdictCache
+// and forwards misses, and every other call, to another backend. With a
+// shared cache such as MmapCache, worker processes on one host validate
+// each distinct input once between them:
+//
+//	cache, err := OpenMmapCache("/var/cache/webp/verdicts", 0)
+//	...
+//	SetBackend(NewCachedBackend(nil, cache))
+//
+// Keys cover the library version and its supported features, so after an
+// upgrade the old entries simply stop matching.
+type CachedBackend struct {
+	next  Backend
+	cache VerdictCache
+	salt  func() ([]byte, error)
+}
+
+// NewCachedBackend returns a CachedBackend in front of next, or of the
+// native library if next is nil.
+func NewCachedBackend(next Backend, cache VerdictCache) *CachedBackend {
+	if next == nil {
+		next = NativeBackend
+	}
+	return &CachedBackend{next: next, cache: cache, salt: sync.OnceValues(func() ([]byte, error) {
+		return librarySalt(next)
+	})}
+}
+
+// librarySalt identifies the library behind b for cache keys.
+func librarySalt(b Backend) ([]byte, error) {
+	version, err := b.Version()
+	if err != nil {
+		return nil, err
+	}
+	features, err := b.SupportedFeatures()
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "webp-validator %s features %#x\x00", version, uint32(features)), nil
+}
+
+// key returns the cache key for data, or false if the library cannot be
+// identified.
+func (b *CachedBackend) key(data []byte) ([sha256.Size]byte, bool) {
+	var key [sha256.Size]byte
+	salt, err := b.salt()
+	if err != nil {
+		return key, false
+	}
+	h := sha256.New()
+	h.Write(salt)
+	h.Write(data)
+	h.Sum(key[:0])
+	return key, true
+}
+
+func (b *CachedBackend) Validate(data []byte) WebpInfo {
+	// Empty input is answered without the library; hashing it costs more.
+	if len(data) == 0 {
+		return b.next.Validate(data)
+	}
+
+	key, ok := b.key(data)
+	if !ok {
+		// Nothing to key on, such as a library that failed to load.
+		return b.next.Validate(data)
+	}
+	if info, ok := b.cache.Get(key); ok {
+		recordVerdictCacheLookup(true)
+		return info
+	}
+	recordVerdictCacheLookup(false)
+
+	info := b.next.Validate(data)
+	// A library that failed to load says nothing about the input.
+	if info.IsValid || classifyFailure(info) != FailureLibrary {
+		b.cache.Put(key, info)
+	}
+	return info
+}
+
+func (b *CachedBackend) Inspect(data []byte) WebpInspection   { return b.next.Inspect(data) }
+func (b *CachedBackend) SupportedFeatures() (Features, error) { return b.next.SupportedFeatures() }
+func (b *CachedBackend) Version() (string, error)             { return b.next.Version() }
+
+func (b *CachedBackend) Decode(data, pixels []byte, durations []uint32) error {
+	return b.next.Decode(data, pixels, durations)
+}
+
+// DefaultCacheSlots is the slot count OpenMmapCache uses for new files
+// when given 0: 65536 slots of mmapSlotSize bytes, a 16 MiB file.
+const DefaultCacheSlots = 1 << 16
+
+const (
+	mmapCacheMagic   = "WEBPVC02"
+	mmapHeaderSize   = 64
+	mmapSlotSize     = 256
+	mmapMaxCacheSize = 1 << 34
+
+	// Slot layout. The checksum covers bytes [mmapSlotKey, mmapSlotSize)
+	// and is stored last, so a reader that sees a matching checksum saw a
+	// complete entry; zero marks an empty slot.
+	mmapSlotChecksum = 0
+	mmapSlotKey      = 8
+	mmapSlotWidth    = mmapSlotKey + sha256.Size
+	mmapSlotHeight   = mmapSlotWidth + 4
+	mmapSlotFrames   = mmapSlotHeight + 4
+	mmapSlotFeatures = mmapSlotFrames + 4
+	mmapSlotFlags    = mmapSlotFeatures + 4
+	mmapSlotErrorLen = mmapSlotFlags + 2
+	mmapSlotError    = mmapSlotErrorLen + 2
+	mmapMaxErrorLen  = mmapSlotSize - mmapSlotError
+)
+
+const (
+	mmapFlagValid = 1 << iota
+	mmapFlagAlpha
+	mmapFlagAnimated
+	mmapFlagPartial
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// MmapCache is a VerdictCache in a memory-mapped file that any number of
+// processes on the host can open at once. The file is a fixed-size,
+// direct-mapped table: each key has one slot and a newer entry evicts
+// whatever was there. Entries carry a checksum instead of a lock, so a
+// process that dies mid-write leaves a slot that reads as empty rather
+// than one that blocks others. Results whose error message does not fit a
+// slot are not cached.
+type MmapCache struct {
+	mu    sync.RWMutex
+	file  *os.File
+	data  []byte
+	slots uint64
+}
+
+// OpenMmapCache opens the cache file at path, creating it with slots
+// entries (DefaultCacheSlots if 0) if it does not exist. An existing file
+// keeps the size it was created with, so workers need not agree on slots.
+func OpenMmapCache(path string, slots int) (*MmapCache, error) {
+	if slots == 0 {
+		slots = DefaultCacheSlots
+	}
+	if slots < 0 || int64(slots)*mmapSlotSize > mmapMaxCacheSize {
+		return nil, fmt.Errorf("invalid verdict cache size: %d slots", slots)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := createCacheFile(path, uint64(slots)); err != nil {
+			return nil, fmt.Errorf("failed to open verdict cache %s: %w", path, err)
+		}
+		file, err = os.OpenFile(path, os.O_RDWR, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open verdict cache: %w", err)
+	}
+	cache, err := mapCacheFile(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open verdict cache %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// createCacheFile builds a complete, empty cache file next to path and
+// links it into place, so no process ever opens a file whose header is
+// still being written. When processes race to create the file, the first
+// link wins and the others open that file instead.
+func createCacheFile(path string, slots uint64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	header := make([]byte, mmapHeaderSize)
+	copy(header, mmapCacheMagic)
+	binary.LittleEndian.PutUint64(header[8:], slots)
+	_, err = tmp.WriteAt(header, 0)
+	if err == nil {
+		err = tmp.Truncate(int64(mmapHeaderSize + slots*mmapSlotSize))
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Link(tmp.Name(), path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// mmapOpenAttempts and mmapOpenRetryDelay bound how long mapCacheFile
+// waits for a header that is missing or all zeros, as left for a moment
+// by writers that create the file in place rather than with
+// createCacheFile.
+var (
+	mmapOpenAttempts   = 20
+	mmapOpenRetryDelay = 10 * time.Millisecond
+)
+
+func mapCacheFile(file *os.File) (*MmapCache, error) {
+	header := make([]byte, mmapHeaderSize)
+	for attempt := 1; ; attempt++ {
+		n, err := file.ReadAt(header, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if n == len(header) && !bytes.Equal(header[:8], make([]byte, 8)) {
+			break
+		}
+		if attempt == mmapOpenAttempts {
+			return nil, errors.New("not a verdict cache file")
+		}
+		time.Sleep(mmapOpenRetryDelay)
+	}
+
+	switch {
+	case string(header[:6]) == mmapCacheMagic[:6] && string(header[:8]) != mmapCacheMagic:
+		return nil, errors.New("verdict cache file has an older layout; delete it to start over")
+	case string(header[:8]) != mmapCacheMagic:
+		return nil, errors.New("not a verdict cache file")
+	}
+
+	slots := binary.LittleEndian.Uint64(header[8:])
+	size := mmapHeaderSize + slots*mmapSlotSize
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if slots == 0 || size > mmapMaxCacheSize || uint64(stat.Size()) != size {
+		return nil, errors.New("verdict cache file is corrupt")
+	}
+
+	data, err := mapFile(file, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return &MmapCache{file: file, data: data, slots: slots}, nil
+}
+
+// Slots returns the number of entries the cache file holds.
+func (c *MmapCache) Slots() int { return int(c.slots) }
+
+func (c *MmapCache) slot(key [sha256.Size]byte) []byte {
+	offset := mmapHeaderSize + binary.LittleEndian.Uint64(key[:8])%c.slots*mmapSlotSize
+	return c.data[offset : offset+mmapSlotSize]
+}
+
+// checksumWord is the first word of a slot, accessed atomically because
+// other processes read and write it concurrently. Slots are 8-byte aligned
+// since the mapping is page aligned and the header and slot sizes are
+// multiples of 8.
+func checksumWord(slot []byte) *uint64 {
+	return (*uint64)(unsafe.Pointer(&slot[mmapSlotChecksum]))
+}
+
+func slotChecksum(entry []byte) uint64 {
+	// Reserve zero for empty slots.
+	return crc64.Checksum(entry[mmapSlotKey:], crcTable) | 1
+}
+
+func (c *MmapCache) Get(key [sha256.Size]byte) (WebpInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return WebpInfo{}, false
+	}
+
+	// Copy the slot first: the checksum check must see the same bytes
+	// that are returned.
+	var entry [mmapSlotSize]byte
+	slot := c.slot(key)
+	checksum := atomic.LoadUint64(checksumWord(slot))
+	copy(entry[:], slot)
+	if checksum == 0 || checksum != slotChecksum(entry[:]) || [sha256.Size]byte(entry[mmapSlotKey:mmapSlotWidth]) != key {
+		return WebpInfo{}, false
+	}
+
+	flags := entry[mmapSlotFlags]
+	errorLen := int(binary.LittleEndian.Uint16(entry[mmapSlotErrorLen:]))
+	if errorLen > mmapMaxErrorLen {
+		return WebpInfo{}, false
+	}
+	return WebpInfo{
+		IsValid:    flags&mmapFlagValid != 0,
+		Width:      binary.LittleEndian.Uint32(entry[mmapSlotWidth:]),
+		Height:     binary.LittleEndian.Uint32(entry[mmapSlotHeight:]),
+		HasAlpha:   flags&mmapFlagAlpha != 0,
+		IsAnimated: flags&mmapFlagAnimated != 0,
+		NumFrames:  binary.LittleEndian.Uint32(entry[mmapSlotFrames:]),
+		Features:   Features(binary.LittleEndian.Uint32(entry[mmapSlotFeatures:])),
+		Partial:    flags&mmapFlagPartial != 0,
+		Error:      string(entry[mmapSlotError : mmapSlotError+errorLen]),
+	}, true
+}
+
+func (c *MmapCache) Put(key [sha256.Size]byte, info WebpInfo) {
+	if len(info.Error) > mmapMaxErrorLen {
+		return
+	}
+
+	var entry [mmapSlotSize]byte
+	copy(entry[mmapSlotKey:], key[:])
+	binary.LittleEndian.PutUint32(entry[mmapSlotWidth:], info.Width)
+	binary.LittleEndian.PutUint32(entry[mmapSlotHeight:], info.Height)
+	binary.LittleEndian.PutUint32(entry[mmapSlotFrames:], info.NumFrames)
+	binary.LittleEndian.PutUint32(entry[mmapSlotFeatures:], uint32(info.Features))
+	if info.IsValid {
+		entry[mmapSlotFlags] |= mmapFlagValid
+	}
+	if info.HasAlpha {
+		entry[mmapSlotFlags] |= mmapFlagAlpha
+	}
+	if info.IsAnimated {
+		entry[mmapSlotFlags] |= mmapFlagAnimated
+	}
+	if info.Partial {
+		entry[mmapSlotFlags] |= mmapFlagPartial
+	}
+	binary.LittleEndian.PutUint16(entry[mmapSlotErrorLen:], uint16(len(info.Error)))
+	copy(entry[mmapSlotError:], info.Error)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return
+	}
+
+	// Clear the checksum so readers miss while the body is rewritten. Two
+	// processes writing one slot at once can interleave, but the result
+	// then fails its checksum and reads as empty.
+	slot := c.slot(key)
+	atomic.StoreUint64(checksumWord(slot), 0)
+	copy(slot[mmapSlotKey:], entry[mmapSlotKey:])
+	atomic.StoreUint64(checksumWord(slot), slotChecksum(entry[:]))
+}
+
+// Close unmaps the file. Entries stay in it for other processes and later
+// opens; Get and Put after Close miss and do nothing.
+func (c *MmapCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil {
+		return nil
+	}
+	err := unmapFile(c.data)
+	c.data = nil
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// UseVerdictCacheEnv puts the MmapCache named by $WEBP_VALIDATOR_CACHE in
+// front of the current backend. The returned function restores it.
+func UseVerdictCacheEnv() (func() error, error) {
+	path := os.Getenv(VerdictCacheEnv)
+	if path == "" {
+		return func() error { return nil }, nil
+	}
+	cache, err := OpenMmapCache(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	previous := SetBackend(NewCachedBackend(currentBackend(), cache))
+	return func() error {
+		SetBackend(previous)
+		return cache.Close()
+	}, nil
+}
@@ -0,0 +1,243 @@
+package webpvalidator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMmapCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verdicts")
+	cache, err := OpenMmapCache(path, 64)
+	require.NoError(t, err)
+
+	valid := WebpInfo{IsValid: true, Width: 640, Height: 480, HasAlpha: true, IsAnimated: true, NumFrames: 12,
+		Features: FeatureAlpha | FeatureAnimation | 1<<31}
+	invalid := WebpInfo{Width: 8, Height: 8, Partial: true, Error: "webp file is truncated: riff header declares 100 bytes, got 50"}
+	validKey, invalidKey := sha256.Sum256([]byte("valid")), sha256.Sum256([]byte("invalid"))
+
+	_, ok := cache.Get(validKey)
+	assert.False(t, ok)
+	cache.Put(validKey, valid)
+	cache.Put(invalidKey, invalid)
+
+	got, ok := cache.Get(validKey)
+	require.True(t, ok)
+	assert.Equal(t, valid, got)
+	got, ok = cache.Get(invalidKey)
+	require.True(t, ok)
+	assert.Equal(t, invalid, got)
+
+	longKey := sha256.Sum256([]byte("long"))
+	cache.Put(longKey, WebpInfo{Error: strings.Repeat("x", mmapMaxErrorLen+1)})
+	_, ok = cache.Get(longKey)
+	assert.False(t, ok, "errors longer than a slot are not cached")
+
+	// Another process opening the file sees the entries and the original
+	// size, whatever size it asks for.
+	other, err := OpenMmapCache(path, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 64, other.Slots())
+	got, ok = other.Get(validKey)
+	require.True(t, ok)
+	assert.Equal(t, valid, got)
+	require.NoError(t, other.Close())
+
+	require.NoError(t, cache.Close())
+	require.NoError(t, cache.Close())
+	_, ok = cache.Get(validKey)
+	assert.False(t, ok)
+	cache.Put(validKey, valid)
+}
+
+func TestMmapCacheTornSlot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verdicts")
+	cache, err := OpenMmapCache(path, 8)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	key := sha256.Sum256(sampleLossy)
+	cache.Put(key, WebpInfo{IsValid: true, Width: 1, Height: 1})
+	// A write that died halfway leaves a body that does not match its
+	// checksum.
+	cache.slot(key)[mmapSlotWidth] ^= 0xff
+	_, ok := cache.Get(key)
+	assert.False(t, ok)
+
+	cache.Put(key, WebpInfo{IsValid: true, Width: 1, Height: 1})
+	_, ok = cache.Get(key)
+	assert.True(t, ok)
+}
+
+func TestMmapCacheConcurrentCreate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "verdicts")
+
+	caches := make([]*MmapCache, 8)
+	errs := make([]error, len(caches))
+	var wg sync.WaitGroup
+	for i := range caches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			caches[i], errs[i] = OpenMmapCache(path, 64*(i+1))
+		}()
+	}
+	wg.Wait()
+
+	for i, cache := range caches {
+		require.NoError(t, errs[i])
+		assert.Equal(t, caches[0].Slots(), cache.Slots(), "every worker opens the file that won")
+		require.NoError(t, cache.Close())
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+func TestMmapCacheWaitsForHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verdicts")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+	// Another process created the file in place and has not written the
+	// header yet.
+	done := make(chan error, 1)
+	go func() {
+		time.Sleep(3 * mmapOpenRetryDelay)
+		header := make([]byte, mmapHeaderSize)
+		copy(header, mmapCacheMagic)
+		binary.LittleEndian.PutUint64(header[8:], 8)
+		done <- os.WriteFile(path, append(header, make([]byte, 8*mmapSlotSize)...), 0o644)
+	}()
+	cache, err := OpenMmapCache(path, 8)
+	require.NoError(t, err)
+	defer cache.Close()
+	require.NoError(t, <-done)
+	assert.Equal(t, 8, cache.Slots())
+
+	attempts := mmapOpenAttempts
+	mmapOpenAttempts = 2
+	defer func() { mmapOpenAttempts = attempts }()
+	empty := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(empty, make([]byte, mmapHeaderSize), 0o644))
+	_, err = OpenMmapCache(empty, 8)
+	assert.ErrorContains(t, err, "not a verdict cache file")
+}
+
+func TestMmapCacheRejectsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "not-a-cache")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0o644))
+	_, err := OpenMmapCache(path, 0)
+	assert.ErrorContains(t, err, "not a verdict cache file")
+
+	path = filepath.Join(dir, "old-layout")
+	require.NoError(t, os.WriteFile(path, []byte("WEBPVC01"+strings.Repeat("\x00", 100)), 0o644))
+	_, err = OpenMmapCache(path, 0)
+	assert.ErrorContains(t, err, "older layout")
+
+	path = filepath.Join(dir, "truncated")
+	cache, err := OpenMmapCache(path, 8)
+	require.NoError(t, err)
+	require.NoError(t, cache.Close())
+	require.NoError(t, os.Truncate(path, mmapHeaderSize+4*mmapSlotSize))
+	_, err = OpenMmapCache(path, 8)
+	assert.ErrorContains(t, err, "corrupt")
+
+	_, err = OpenMmapCache(filepath.Join(dir, "negative"), -1)
+	assert.Error(t, err)
+}
+
+func TestCachedBackend(t *testing.T) {
+	cache, err := OpenMmapCache(filepath.Join(t.TempDir(), "verdicts"), 64)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	calls := 0
+	previous := SetBackend(NewCachedBackend(hookBackend{func() { calls++ }}, cache))
+	defer SetBackend(previous)
+
+	before := Stats()
+	first := ValidateWebp(sampleLossy)
+	second := ValidateWebp(sampleLossy)
+	ValidateWebp(nil)
+	after := Stats()
+
+	assert.True(t, first.IsValid)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 2, calls, "the repeat is served from the cache, empty input is not cached")
+	assert.Equal(t, uint64(1), after.VerdictCacheHits-before.VerdictCacheHits)
+	assert.Equal(t, uint64(1), after.VerdictCacheMisses-before.VerdictCacheMisses)
+	assert.Equal(t, uint64(3), after.Validations-before.Validations)
+}
+
+func TestCachedBackendSkipsLibraryFailures(t *testing.T) {
+	cache, err := OpenMmapCache(filepath.Join(t.TempDir(), "verdicts"), 64)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	failing := NewCachedBackend(failingBackend{}, cache)
+	before := Stats()
+	failing.Validate(sampleLossy)
+	_, ok := failing.key(sampleLossy)
+	assert.False(t, ok, "an unidentified library has no cache key")
+	assert.Equal(t, before.VerdictCacheMisses, Stats().VerdictCacheMisses)
+}
+
+// versionedBackend is NativeBackend reporting another library version.
+type versionedBackend struct {
+	Backend
+	version string
+}
+
+func (b versionedBackend) Version() (string, error) { return b.version, nil }
+
+func TestCachedBackendKeysOnLibrary(t *testing.T) {
+	cache, err := OpenMmapCache(filepath.Join(t.TempDir(), "verdicts"), 64)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	old := NewCachedBackend(versionedBackend{NativeBackend, "1.0.0"}, cache)
+	upgraded := NewCachedBackend(versionedBackend{NativeBackend, "1.1.0"}, cache)
+	old.Validate(sampleLossy)
+
+	oldKey, ok := old.key(sampleLossy)
+	require.True(t, ok)
+	newKey, ok := upgraded.key(sampleLossy)
+	require.True(t, ok)
+	assert.NotEqual(t, oldKey, newKey)
+	_, ok = cache.Get(oldKey)
+	assert.True(t, ok)
+	_, ok = cache.Get(newKey)
+	assert.False(t, ok, "an upgraded library starts with a cold cache")
+}
+
+// failingBackend reports the native library as missing.
+type failingBackend struct{}
+
+func (failingBackend) Validate([]byte) WebpInfo {
+	return WebpInfo{Error: "failed to load native library libwebp_validator.so: not found"}
+}
+
+func (failingBackend) Inspect([]byte) WebpInspection { return WebpInspection{} }
+
+func (failingBackend) Decode([]byte, []byte, []uint32) error {
+	return errors.New("failed to load native library libwebp_validator.so: not found")
+}
+
+func (failingBackend) SupportedFeatures() (Features, error) {
+	return 0, errors.New("failed to load native library libwebp_validator.so: not found")
+}
+
+func (failingBackend) Version() (string, error) {
+	return "", errors.New("failed to load native library libwebp_validator.so: not found")
+}
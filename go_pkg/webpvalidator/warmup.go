@@ -0,0 +1,67 @@
+package webpvalidator
+
+import (
+	"context"
+	"fmt"
+)
+
+// warmupSample is an embedded input and the result it must produce.
+type warmupSample struct {
+	name     string
+	data     []byte
+	valid    bool
+	animated bool
+}
+
+func warmupSamples() []warmupSample {
+	return []warmupSample{
+		{"lossy", sampleLossy, true, false},
+		{"lossless", sampleLossless, true, false},
+		{"alpha", sampleAlpha, true, false},
+		{"animated", sampleAnimated, true, true},
+		// Exercises the failure path, including partial metadata recovery.
+		{"truncated", sampleAnimated[:len(sampleAnimated)-4], false, true},
+	}
+}
+
+// Warmup runs the embedded samples through every native code path
+// (static, animated, invalid, container inspection and pixel decoding), so
+// one-time setup costs are paid before the first real request rather than
+// during it. It calls the native library directly, bypassing SetBackend,
+// and is not counted in Stats.
+//
+// A nil error means the native library is loaded and behaves as expected;
+// a loader failure is returned as is.
+//
+// Warmup returns ctx.Err() if ctx is done first, and an error naming the
+// sample if one does not produce the expected result.
+func Warmup(ctx context.Context) error {
+	if _, err := LoadNativeLibrary(); err != nil {
+		return err
+	}
+	for _, sample := range warmupSamples() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info := NativeBackend.Validate(sample.data)
+		if info.IsValid != sample.valid || info.IsAnimated != sample.animated {
+			return fmt.Errorf("warmup sample %s: got valid=%v animated=%v (%s), want valid=%v animated=%v",
+				sample.name, info.IsValid, info.IsAnimated, info.Error, sample.valid, sample.animated)
+		}
+		if inspection := NativeBackend.Inspect(sample.data); len(inspection.Chunks) == 0 {
+			return fmt.Errorf("warmup sample %s: no chunks found", sample.name)
+		}
+		if !info.IsValid {
+			continue
+		}
+		frames, err := decodeFrames(NativeBackend.Decode, sample.data, info, frameCount(info))
+		if err != nil {
+			return fmt.Errorf("warmup sample %s: %w", sample.name, err)
+		}
+		if len(frames) != frameCount(info) {
+			return fmt.Errorf("warmup sample %s: decoded %d frames, want %d", sample.name, len(frames), frameCount(info))
+		}
+	}
+	return nil
+}
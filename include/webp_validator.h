@@ -22,13 +22,35 @@ extern "C"
         uint32_t num_frames; // Number of frames (for animated WebP)
         char *error_message; // Error message (NULL if is_valid is true)
                              // Free using free_error_message()
+        // Later fields are appended only, never inserted, so callers
+        // compiled against an older header keep a compatible layout.
+        bool is_partial;     // Whether the metadata fields are best-effort,
+                             // recovered from a file that failed validation
+        uint32_t features;   // WEBP_FEATURE_* bits; unknown bits may be set
+                             // by newer libraries and must be preserved
     } WebpValidationResult;
 
+/*
+ * Feature bits in WebpValidationResult.features. Bits are only ever added;
+ * webp_supported_features_ffi() returns the ones a library can report.
+ */
+#define WEBP_FEATURE_ALPHA (1u << 0)
+#define WEBP_FEATURE_ANIMATION (1u << 1)
+#define WEBP_FEATURE_ICC (1u << 2)
+#define WEBP_FEATURE_EXIF (1u << 3)
+#define WEBP_FEATURE_XMP (1u << 4)
+#define WEBP_FEATURE_LOSSLESS (1u << 5)   // At least one VP8L bitstream
+#define WEBP_FEATURE_FRAGMENTS (1u << 6)  // Obsolete fragment extension
+
     /**
      * Validate WebP image file
      *
+     * Inputs larger than 8 + UINT32_MAX bytes cannot be a single RIFF
+     * container and are rejected, as are inputs shorter than the size
+     * declared in their RIFF header.
+     *
      * @param data Pointer to WebP file data
-     * @param len Length of the data in bytes
+     * @param len Length of the data in bytes (64-bit on 64-bit targets)
      * @return WebpValidationResult
      */
     WebpValidationResult validate_webp_ffi(const uint8_t *data, size_t len);
@@ -40,6 +62,90 @@ extern "C"
      */
     void free_error_message(char *error_message);
 
+    /**
+     * Every WEBP_FEATURE_* bit this library can report
+     *
+     * @return Bit mask of supported features
+     */
+    uint32_t webp_supported_features_ffi(void);
+
+    /**
+     * Version of this library build
+     *
+     * @return Static NUL-terminated string; do not free it
+     */
+    const char *webp_validator_version_ffi(void);
+
+    /**
+     * Location of a chunk in the RIFF container
+     */
+    typedef struct
+    {
+        uint8_t fourcc[4]; // Chunk identifier, e.g. "VP8X"
+        uint64_t offset;   // Offset of the chunk header from start of file
+        uint32_t size;     // Payload size declared in the header (no padding)
+        uint32_t depth;    // 0 for top level, 1 for chunks inside ANMF
+    } WebpChunkInfo;
+
+    /**
+     * Structural problem located at a byte range
+     */
+    typedef struct
+    {
+        uint64_t offset; // First byte of the affected range
+        uint64_t length; // Length of the affected range (may be 0)
+        bool is_warning; // Readable but not strictly conformant
+        char *message;   // Description of the problem
+    } WebpFindingInfo;
+
+    /**
+     * Container structure of a WebP file
+     */
+    typedef struct
+    {
+        WebpChunkInfo *chunks;     // Complete chunks in file order
+        size_t num_chunks;
+        WebpFindingInfo *findings; // Structural findings
+        size_t num_findings;
+    } WebpInspectionResult;
+
+    /**
+     * Walk the container structure, locating every chunk and every
+     * structural problem by byte range
+     *
+     * @param data Pointer to WebP file data
+     * @param len Length of the data in bytes
+     * @return WebpInspectionResult, free using free_webp_inspection()
+     */
+    WebpInspectionResult inspect_webp_ffi(const uint8_t *data, size_t len);
+
+    /**
+     * Free memory allocated by inspect_webp_ffi
+     *
+     * @param result Value returned by inspect_webp_ffi
+     */
+    void free_webp_inspection(WebpInspectionResult result);
+
+    /**
+     * Decode every frame as 8-bit RGBA into caller-owned buffers
+     *
+     * Size the buffers from validate_webp_ffi: up to one frame per
+     * animation frame (num_frames), or a single frame for a still image,
+     * each width * height * 4 bytes, stored back to back. Passing fewer
+     * frames decodes only that many from the start of the animation.
+     *
+     * @param data Pointer to WebP file data
+     * @param len Length of the data in bytes
+     * @param pixels Output buffer for all frames
+     * @param pixels_len Length of pixels in bytes
+     * @param durations Output frame durations in milliseconds (0 for stills)
+     * @param num_frames Number of entries in durations
+     * @return NULL on success, or an error message to free using
+     *         free_error_message()
+     */
+    char *decode_webp_ffi(const uint8_t *data, size_t len, uint8_t *pixels, size_t pixels_len,
+                          uint32_t *durations, size_t num_frames);
+
 #ifdef __cplusplus
 }
 #endif
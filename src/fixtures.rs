@@ -0,0 +1,53 @@
+//! Test inputs built in memory, from the same 1x1 samples as the Go
+//! fixture set, so the tests need no image files.
+
+/// 1x1 lossy (VP8)
+pub const LOSSY: &[u8] = b"RIFF\x22\x00\x00\x00WEBPVP8 \x16\x00\x00\x000\x01\x00\x9d\x01*\x01\x00\x01\x00\x0e\xc0\xfe%\xa4\x00\x03p\x00\x00\x00\x00";
+
+/// 1x1 lossless (VP8L)
+pub const LOSSLESS: &[u8] = b"RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00/\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00";
+
+/// 1x1 lossy with an ALPH chunk (VP8X, ALPH, VP8)
+pub const ALPHA: &[u8] = b"RIFFJ\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x10\x00\x00\x00\x00\x00\x00\x00\x00\x00ALPH\x0c\x00\x00\x00\x11\x07\x10\x11\xfd\x0fDD\xff\x03\x00\x00VP8 \x18\x00\x00\x00\x14\x01\x00\x9d\x01*\x01\x00\x01\x00\x00\x00\xfe\x00\x00\x0d\xc0\x00\xfe\xe6\xb5\x00\x00\x00";
+
+/// PNG signature and header, as found in a file misnamed .webp
+pub const NOT_WEBP: &[u8] =
+    b"\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00";
+
+/// RIFF/WEBP container holding `chunks` (fourcc, payload)
+pub fn container(chunks: &[(&[u8; 4], &[u8])]) -> Vec<u8> {
+    let mut data = b"RIFF\0\0\0\0WEBP".to_vec();
+    for (fourcc, payload) in chunks {
+        data.extend_from_slice(*fourcc);
+        data.extend_from_slice(&(payload.len() as u32).to_le_bytes());
+        data.extend_from_slice(payload);
+        if payload.len() % 2 == 1 {
+            data.push(0);
+        }
+    }
+    let size = (data.len() - 8) as u32;
+    data[4..8].copy_from_slice(&size.to_le_bytes());
+    data
+}
+
+/// 1x1 animation of three lossless frames lasting 100, 200 and 300ms
+pub fn animated() -> Vec<u8> {
+    let vp8x = [0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0];
+    // Background color, then loop count 0 (forever).
+    let anim = [0xff, 0xff, 0xff, 0xff, 0, 0];
+    let frames: Vec<Vec<u8>> = [100u32, 200, 300]
+        .iter()
+        .map(|duration| {
+            // X/2, Y/2, width-1 and height-1 (all 0), a 24-bit duration, flags.
+            let mut frame = vec![0u8; 16];
+            frame[12..15].copy_from_slice(&duration.to_le_bytes()[..3]);
+            // The whole VP8L chunk of the lossless sample.
+            frame.extend_from_slice(&LOSSLESS[12..]);
+            frame
+        })
+        .collect();
+
+    let mut chunks: Vec<(&[u8; 4], &[u8])> = vec![(b"VP8X", &vp8x), (b"ANIM", &anim)];
+    chunks.extend(frames.iter().map(|frame| (b"ANMF", frame.as_slice())));
+    container(&chunks)
+}
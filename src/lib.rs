@@ -1,8 +1,33 @@
+pub mod riff;
+
+#[cfg(test)]
+mod fixtures;
+
 use image_webp::WebPDecoder;
 use std::ffi::CString;
 use std::io::Cursor;
 use std::os::raw::c_char;
 
+/// Feature bits reported in `WebpInfo::features` and across the FFI.
+///
+/// Bits are only ever added, never reused; consumers must carry bits they
+/// do not know through unchanged. `SUPPORTED` is every bit this build can
+/// report.
+pub mod features {
+    pub const ALPHA: u32 = 1 << 0;
+    pub const ANIMATION: u32 = 1 << 1;
+    pub const ICC: u32 = 1 << 2;
+    pub const EXIF: u32 = 1 << 3;
+    pub const XMP: u32 = 1 << 4;
+    /// At least one VP8L (lossless) bitstream
+    pub const LOSSLESS: u32 = 1 << 5;
+    /// The obsolete fragmented-image extension: the VP8X fragments flag or
+    /// FRGM chunks
+    pub const FRAGMENTS: u32 = 1 << 6;
+
+    pub const SUPPORTED: u32 = ALPHA | ANIMATION | ICC | EXIF | XMP | LOSSLESS | FRAGMENTS;
+}
+
 /// WebP image information
 #[derive(Debug)]
 pub struct WebpInfo {
@@ -11,30 +36,251 @@ pub struct WebpInfo {
     pub has_alpha: bool,
     pub is_animated: bool,
     pub num_frames: u32,
+    /// `features` bits, from the decoder and the container
+    pub features: u32,
 }
 
 impl WebpInfo {
-    fn new_valid(decoder: &WebPDecoder<Cursor<&[u8]>>) -> Self {
-        WebpInfo {
+    fn new_valid(decoder: &WebPDecoder<Cursor<&[u8]>>, data: &[u8]) -> Self {
+        let mut info = WebpInfo {
             width: decoder.dimensions().0,
             height: decoder.dimensions().1,
             has_alpha: decoder.has_alpha(),
             is_animated: decoder.is_animated(),
             num_frames: decoder.num_frames(),
+            features: container_features(data),
+        };
+        if info.has_alpha {
+            info.features |= features::ALPHA;
+        }
+        if info.is_animated {
+            info.features |= features::ANIMATION;
+        }
+        info
+    }
+}
+
+/// Features declared by the VP8X flags or present as chunks, at any depth.
+/// The container is walked independently of the decoder, so this works on
+/// files that failed validation too.
+pub fn container_features(data: &[u8]) -> u32 {
+    let mut found = 0;
+    for chunk in riff::walk(data).chunks {
+        let payload = chunk.payload(data).unwrap_or_default();
+        found |= match &chunk.fourcc {
+            b"VP8X" if !payload.is_empty() => {
+                let flags = payload[0];
+                [
+                    (0x20, features::ICC),
+                    (0x10, features::ALPHA),
+                    (0x08, features::EXIF),
+                    (0x04, features::XMP),
+                    (0x02, features::ANIMATION),
+                    (0x01, features::FRAGMENTS),
+                ]
+                .iter()
+                .filter(|(bit, _)| flags & bit != 0)
+                .fold(0, |acc, (_, feature)| acc | feature)
+            }
+            b"ICCP" => features::ICC,
+            b"EXIF" => features::EXIF,
+            b"XMP " => features::XMP,
+            b"ALPH" => features::ALPHA,
+            b"ANIM" | b"ANMF" => features::ANIMATION,
+            b"VP8L" => features::LOSSLESS,
+            b"FRGM" => features::FRAGMENTS,
+            _ => 0,
+        };
+    }
+    found
+}
+
+/// Largest file a RIFF container can describe: the 8-byte RIFF header
+/// followed by a payload whose size is stored as a u32.
+pub const MAX_RIFF_FILE_SIZE: u64 = 8 + u32::MAX as u64;
+
+/// Reject inputs that cannot be a single RIFF container.
+///
+/// All arithmetic is done in u64 so a declared size near u32::MAX
+/// cannot wrap around on 32-bit targets.
+fn check_riff_size(data: &[u8]) -> Result<(), String> {
+    let len = data.len() as u64;
+    if len > MAX_RIFF_FILE_SIZE {
+        return Err(format!(
+            "webp file exceeds riff size limit: {} bytes (max {})",
+            len, MAX_RIFF_FILE_SIZE
+        ));
+    }
+
+    if data.len() >= 8 && &data[0..4] == b"RIFF" {
+        let declared = u32::from_le_bytes([data[4], data[5], data[6], data[7]]) as u64 + 8;
+        if declared > len {
+            return Err(format!(
+                "webp file is truncated: riff header declares {} bytes, got {}",
+                declared, len
+            ));
         }
     }
+
+    Ok(())
 }
 
 /// Validate WebP image format
 pub fn validate_webp(data: &[u8]) -> Result<WebpInfo, String> {
+    check_riff_size(data)?;
+
     let reader = Cursor::new(data);
 
     match WebPDecoder::new(reader) {
-        Ok(decoder) => Ok(WebpInfo::new_valid(&decoder)),
+        Ok(decoder) => Ok(WebpInfo::new_valid(&decoder, data)),
         Err(e) => Err(format!("webp format validation failed: {:?}", e)),
     }
 }
 
+/// Number of frames `decode_webp_rgba` produces: one per animation frame,
+/// or a single frame for a still image.
+pub fn decoded_frame_count(info: &WebpInfo) -> u32 {
+    if info.is_animated {
+        info.num_frames
+    } else {
+        1
+    }
+}
+
+/// Decode frames as 8-bit RGBA into caller-provided buffers.
+///
+/// `durations` has one entry per frame to decode, between one and
+/// `decoded_frame_count`, so callers that only need the first frames of an
+/// animation do not pay for the rest. `pixels` must hold that many frames
+/// of `width * height * 4` bytes each, back to back. Animation frames are
+/// the composited canvas; still images get a duration of 0.
+pub fn decode_webp_rgba(
+    data: &[u8],
+    pixels: &mut [u8],
+    durations: &mut [u32],
+) -> Result<(), String> {
+    check_riff_size(data)?;
+
+    let mut decoder = WebPDecoder::new(Cursor::new(data))
+        .map_err(|e| format!("webp format validation failed: {:?}", e))?;
+    let info = WebpInfo::new_valid(&decoder, data);
+    let frames = durations.len();
+    let frame_len = (info.width as u64) * (info.height as u64) * 4;
+    if frames == 0
+        || frames > decoded_frame_count(&info) as usize
+        || pixels.len() as u64 != frame_len * frames as u64
+    {
+        return Err(format!(
+            "decode buffers do not match image: {}x{} with {} frames",
+            info.width, info.height, frames
+        ));
+    }
+
+    // The decoder writes RGB for images without alpha; expand those to RGBA
+    // so every caller sees a single pixel layout.
+    let mut rgb = if info.has_alpha {
+        Vec::new()
+    } else {
+        vec![
+            0u8;
+            decoder
+                .output_buffer_size()
+                .ok_or("image too large to decode")?
+        ]
+    };
+    let frame_len = frame_len as usize;
+    for (frame, duration) in pixels.chunks_exact_mut(frame_len).zip(durations.iter_mut()) {
+        let target = if info.has_alpha {
+            &mut *frame
+        } else {
+            &mut rgb[..]
+        };
+        let result = if info.is_animated {
+            decoder.read_frame(target).map(|ms| *duration = ms)
+        } else {
+            decoder.read_image(target)
+        };
+        result.map_err(|e| format!("webp decode failed: {:?}", e))?;
+
+        if !info.has_alpha {
+            for (out, pixel) in frame.chunks_exact_mut(4).zip(rgb.chunks_exact(3)) {
+                out[..3].copy_from_slice(pixel);
+                out[3] = 0xff;
+            }
+        }
+    }
+
+    Ok(())
+}
+
+/// Best-effort metadata for data that failed validation.
+///
+/// Walks the readable chunks and collects dimensions (from VP8X, or the
+/// first VP8/VP8L bitstream header), the alpha and animation flags, and
+/// the number of complete animation frames seen before the data ends or
+/// becomes unreadable. Returns None if nothing could be recovered.
+pub fn partial_webp_info(data: &[u8]) -> Option<WebpInfo> {
+    let mut info = WebpInfo {
+        width: 0,
+        height: 0,
+        has_alpha: false,
+        is_animated: false,
+        num_frames: 0,
+        features: container_features(data),
+    };
+    let mut found = false;
+
+    for chunk in riff::chunks(data) {
+        let payload = chunk.payload(data).unwrap_or_default();
+        match &chunk.fourcc {
+            b"VP8X" if payload.len() >= 10 => {
+                info.has_alpha = payload[0] & 0x10 != 0;
+                info.is_animated = payload[0] & 0x02 != 0;
+                info.width = riff::read_u24(&payload[4..7]) + 1;
+                info.height = riff::read_u24(&payload[7..10]) + 1;
+                found = true;
+            }
+            b"ANMF" => {
+                info.num_frames += 1;
+                found = true;
+            }
+            b"ALPH" => {
+                info.has_alpha = true;
+                found = true;
+            }
+            b"VP8 " if info.width == 0 => {
+                if let Some((width, height)) = riff::vp8_dimensions(payload) {
+                    info.width = width;
+                    info.height = height;
+                    found = true;
+                }
+            }
+            b"VP8L" if info.width == 0 => {
+                if let Some((width, height, has_alpha)) = riff::vp8l_header(payload) {
+                    info.width = width;
+                    info.height = height;
+                    info.has_alpha |= has_alpha;
+                    found = true;
+                }
+            }
+            _ => {}
+        }
+    }
+
+    if info.has_alpha {
+        info.features |= features::ALPHA;
+    }
+    if info.num_frames > 0 {
+        info.features |= features::ANIMATION;
+    }
+
+    if found {
+        Some(info)
+    } else {
+        None
+    }
+}
+
 /// C-compatible WebP validation result
 #[repr(C)]
 pub struct WebpValidationResult {
@@ -45,6 +291,10 @@ pub struct WebpValidationResult {
     pub is_animated: bool,
     pub num_frames: u32,
     pub error_message: *mut c_char,
+    // Fields below were added after the first release; new fields are only
+    // ever appended so existing C callers keep their layout.
+    pub is_partial: bool,
+    pub features: u32,
 }
 
 /// Validate WebP file via FFI
@@ -63,7 +313,9 @@ pub unsafe extern "C" fn validate_webp_ffi(data: *const u8, len: usize) -> WebpV
             has_alpha: false,
             is_animated: false,
             num_frames: 0,
+            is_partial: false,
             error_message: CString::new("data pointer is null").unwrap().into_raw(),
+            features: 0,
         };
     }
 
@@ -77,20 +329,74 @@ pub unsafe extern "C" fn validate_webp_ffi(data: *const u8, len: usize) -> WebpV
             has_alpha: info.has_alpha,
             is_animated: info.is_animated,
             num_frames: info.num_frames,
+            is_partial: false,
             error_message: std::ptr::null_mut(),
+            features: info.features,
         },
-        Err(err) => WebpValidationResult {
-            is_valid: false,
-            width: 0,
-            height: 0,
-            has_alpha: false,
-            is_animated: false,
-            num_frames: 0,
-            error_message: CString::new(err).unwrap().into_raw(),
+        Err(err) => match partial_webp_info(slice) {
+            Some(info) => WebpValidationResult {
+                is_valid: false,
+                width: info.width,
+                height: info.height,
+                has_alpha: info.has_alpha,
+                is_animated: info.is_animated,
+                num_frames: info.num_frames,
+                is_partial: true,
+                error_message: CString::new(err).unwrap().into_raw(),
+                features: info.features,
+            },
+            None => WebpValidationResult {
+                is_valid: false,
+                width: 0,
+                height: 0,
+                has_alpha: false,
+                is_animated: false,
+                num_frames: 0,
+                is_partial: false,
+                error_message: CString::new(err).unwrap().into_raw(),
+                features: 0,
+            },
         },
     }
 }
 
+/// Decode the leading `num_frames` frames as RGBA via FFI into caller-owned
+/// buffers sized from a prior `validate_webp_ffi` call (see `decode_webp_rgba`)
+///
+/// Returns null on success, or an error message.
+///
+/// # Safety
+/// Caller must ensure:
+/// 1. `data` is a valid pointer to a byte array of length `len`
+/// 2. `pixels` and `durations` are valid for writes of `pixels_len` bytes
+///    and `num_frames` values
+/// 3. A returned error message is freed using `free_error_message`
+#[no_mangle]
+pub unsafe extern "C" fn decode_webp_ffi(
+    data: *const u8,
+    len: usize,
+    pixels: *mut u8,
+    pixels_len: usize,
+    durations: *mut u32,
+    num_frames: usize,
+) -> *mut c_char {
+    if data.is_null() || pixels.is_null() || durations.is_null() {
+        return CString::new("data pointer is null").unwrap().into_raw();
+    }
+
+    let (data, pixels, durations) = unsafe {
+        (
+            std::slice::from_raw_parts(data, len),
+            std::slice::from_raw_parts_mut(pixels, pixels_len),
+            std::slice::from_raw_parts_mut(durations, num_frames),
+        )
+    };
+    match decode_webp_rgba(data, pixels, durations) {
+        Ok(()) => std::ptr::null_mut(),
+        Err(err) => CString::new(err).unwrap().into_raw(),
+    }
+}
+
 /// Free error message memory allocated by validate_webp_ffi
 ///
 /// # Safety
@@ -106,14 +412,138 @@ pub unsafe extern "C" fn free_error_message(error_message: *mut c_char) {
     }
 }
 
+/// Every feature bit this build of the library can report
+/// (`features::SUPPORTED`), so bindings can tell bits that are unset from
+/// bits their library does not know
+#[no_mangle]
+pub extern "C" fn webp_supported_features_ffi() -> u32 {
+    features::SUPPORTED
+}
+
+/// Version of this build of the library (the crate version), as a
+/// NUL-terminated string with static lifetime; callers must not free it
+#[no_mangle]
+pub extern "C" fn webp_validator_version_ffi() -> *const c_char {
+    concat!(env!("CARGO_PKG_VERSION"), "\0").as_ptr() as *const c_char
+}
+
+/// C-compatible chunk location
+#[repr(C)]
+pub struct WebpChunkInfo {
+    pub fourcc: [u8; 4],
+    pub offset: u64,
+    pub size: u32,
+    pub depth: u32,
+}
+
+/// C-compatible structural finding
+#[repr(C)]
+pub struct WebpFindingInfo {
+    pub offset: u64,
+    pub length: u64,
+    pub is_warning: bool,
+    pub message: *mut c_char,
+}
+
+/// C-compatible result of walking the container structure
+#[repr(C)]
+pub struct WebpInspectionResult {
+    pub chunks: *mut WebpChunkInfo,
+    pub num_chunks: usize,
+    pub findings: *mut WebpFindingInfo,
+    pub num_findings: usize,
+}
+
+/// Hand a Vec to C as a pointer/length pair, freed by `take_boxed_slice`
+fn into_raw_slice<T>(items: Vec<T>) -> (*mut T, usize) {
+    let len = items.len();
+    if len == 0 {
+        return (std::ptr::null_mut(), 0);
+    }
+    (Box::into_raw(items.into_boxed_slice()) as *mut T, len)
+}
+
+/// Reclaim a pointer/length pair created by `into_raw_slice`
+///
+/// # Safety
+/// `ptr` and `len` must come from a single `into_raw_slice` call.
+unsafe fn take_boxed_slice<T>(ptr: *mut T, len: usize) -> Box<[T]> {
+    if ptr.is_null() {
+        return Box::new([]);
+    }
+    unsafe { Box::from_raw(std::ptr::slice_from_raw_parts_mut(ptr, len)) }
+}
+
+/// Walk the container structure via FFI, locating every chunk and every
+/// structural problem by byte range
+///
+/// # Safety
+/// Caller must ensure:
+/// 1. `data` is a valid pointer to a byte array of length `len`
+/// 2. The result is freed using `free_webp_inspection`
+#[no_mangle]
+pub unsafe extern "C" fn inspect_webp_ffi(data: *const u8, len: usize) -> WebpInspectionResult {
+    let walk = if data.is_null() {
+        riff::walk(&[])
+    } else {
+        riff::walk(unsafe { std::slice::from_raw_parts(data, len) })
+    };
+
+    let chunks = walk
+        .chunks
+        .iter()
+        .map(|chunk| WebpChunkInfo {
+            fourcc: chunk.fourcc,
+            offset: chunk.offset,
+            size: chunk.size,
+            depth: chunk.depth,
+        })
+        .collect();
+    let findings = walk
+        .findings
+        .into_iter()
+        .map(|finding| WebpFindingInfo {
+            offset: finding.offset,
+            length: finding.length,
+            is_warning: finding.severity == riff::Severity::Warning,
+            message: CString::new(finding.message).unwrap().into_raw(),
+        })
+        .collect();
+
+    let (chunks, num_chunks) = into_raw_slice(chunks);
+    let (findings, num_findings) = into_raw_slice(findings);
+    WebpInspectionResult {
+        chunks,
+        num_chunks,
+        findings,
+        num_findings,
+    }
+}
+
+/// Free memory allocated by inspect_webp_ffi
+///
+/// # Safety
+/// Caller must ensure:
+/// 1. `result` was returned by `inspect_webp_ffi`
+/// 2. This function is called only once per result
+#[no_mangle]
+pub unsafe extern "C" fn free_webp_inspection(result: WebpInspectionResult) {
+    unsafe {
+        drop(take_boxed_slice(result.chunks, result.num_chunks));
+        for finding in take_boxed_slice(result.findings, result.num_findings).iter() {
+            free_error_message(finding.message);
+        }
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
-    use std::fs;
+    use crate::fixtures::{self, container, ALPHA, LOSSY, NOT_WEBP};
 
     #[test]
     fn test_validate_dynamic_webp() {
-        let data = fs::read("images/dynamic.webp").expect("failed to read file");
+        let data = fixtures::animated();
         let result = validate_webp(&data);
 
         assert!(result.is_ok(), "dynamic webp should pass validation");
@@ -138,7 +568,7 @@ mod tests {
 
     #[test]
     fn test_validate_static_webp() {
-        let data = fs::read("images/static.webp").expect("failed to read file");
+        let data = ALPHA.to_vec();
         let result = validate_webp(&data);
 
         assert!(result.is_ok(), "static webp should pass validation");
@@ -158,7 +588,7 @@ mod tests {
 
     #[test]
     fn test_validate_fake_webp() {
-        let data = fs::read("images/fake.webp").expect("failed to read file");
+        let data = NOT_WEBP.to_vec();
         let result = validate_webp(&data);
 
         assert!(result.is_err(), "fake webp should fail validation");
@@ -178,9 +608,172 @@ mod tests {
         println!("  error message: {}", error);
     }
 
+    #[test]
+    fn test_validate_truncated_webp() {
+        let data = fixtures::animated();
+        let result = validate_webp(&data[..data.len() / 2]);
+
+        assert!(result.is_err(), "truncated webp should fail validation");
+        let error = result.unwrap_err();
+        assert!(
+            error.contains("webp file is truncated"),
+            "error should report truncation, actual: {}",
+            error
+        );
+    }
+
+    #[test]
+    fn test_partial_info_truncated_animation() {
+        let data = fixtures::animated();
+        let full = validate_webp(&data).expect("dynamic webp should be valid");
+
+        let info = partial_webp_info(&data[..data.len() / 2]).expect("should recover metadata");
+        assert_eq!(info.width, full.width, "width should come from VP8X");
+        assert_eq!(info.height, full.height, "height should come from VP8X");
+        assert!(info.is_animated, "animation flag should come from VP8X");
+        assert!(
+            info.num_frames > 0 && info.num_frames < full.num_frames,
+            "should count only complete frames, actual: {}",
+            info.num_frames
+        );
+    }
+
+    #[test]
+    fn test_partial_info_fake_webp() {
+        let data = NOT_WEBP.to_vec();
+        assert!(
+            partial_webp_info(&data).is_none(),
+            "non-webp data should have no partial metadata"
+        );
+    }
+
+    #[test]
+    fn test_riff_size_does_not_overflow() {
+        // A declared size of u32::MAX would wrap to 7 in 32-bit arithmetic.
+        let data = [
+            b'R', b'I', b'F', b'F', 0xff, 0xff, 0xff, 0xff, b'W', b'E', b'B', b'P',
+        ];
+        let error = check_riff_size(&data).unwrap_err();
+        assert!(
+            error.contains(&format!("declares {} bytes", MAX_RIFF_FILE_SIZE)),
+            "declared size should be computed in 64 bits, actual: {}",
+            error
+        );
+    }
+
+    #[test]
+    fn test_inspect_ffi_roundtrip() {
+        let data = ALPHA.to_vec();
+        let truncated = &data[..data.len() - 1];
+
+        unsafe {
+            let result = inspect_webp_ffi(truncated.as_ptr(), truncated.len());
+            let chunks = std::slice::from_raw_parts(result.chunks, result.num_chunks);
+            let findings = std::slice::from_raw_parts(result.findings, result.num_findings);
+
+            assert_eq!(&chunks[0].fourcc, b"VP8X");
+            assert_eq!(chunks[0].offset, 12);
+            assert!(!findings.is_empty(), "truncated file should have findings");
+            assert!(!findings[0].message.is_null());
+
+            free_webp_inspection(result);
+        }
+    }
+
+    #[test]
+    fn test_decode_webp_rgba() {
+        for (name, data) in [
+            ("lossy", LOSSY.to_vec()),
+            ("alpha", ALPHA.to_vec()),
+            ("animated", fixtures::animated()),
+        ] {
+            let info = validate_webp(&data).expect("webp should be valid");
+            let frames = decoded_frame_count(&info) as usize;
+            let mut pixels = vec![0u8; info.width as usize * info.height as usize * 4 * frames];
+            let mut durations = vec![0u32; frames];
+
+            decode_webp_rgba(&data, &mut pixels, &mut durations).expect("webp should decode");
+            if info.is_animated {
+                assert!(
+                    durations.iter().any(|&ms| ms > 0),
+                    "{}: frames should have durations",
+                    name
+                );
+            }
+            if !info.has_alpha {
+                assert!(
+                    pixels.chunks_exact(4).all(|p| p[3] == 0xff),
+                    "{}: should be opaque",
+                    name
+                );
+            }
+
+            let frame_len = pixels.len() / frames;
+            let mut first = vec![0u8; frame_len];
+            decode_webp_rgba(&data, &mut first, &mut durations[..1])
+                .expect("first frame should decode");
+            assert_eq!(first, pixels[..frame_len], "{}: first frame differs", name);
+
+            let mut short = vec![0u8; pixels.len() - 1];
+            assert!(decode_webp_rgba(&data, &mut short, &mut durations).is_err());
+            let mut extra = vec![0u32; frames + 1];
+            let mut more = vec![0u8; frame_len * (frames + 1)];
+            assert!(decode_webp_rgba(&data, &mut more, &mut extra).is_err());
+            assert!(decode_webp_rgba(&data, &mut [], &mut []).is_err());
+        }
+    }
+
+    #[test]
+    fn test_result_layout_is_append_only() {
+        use std::mem::offset_of;
+
+        // The first release's fields, in order, then every later addition.
+        let offsets = [
+            offset_of!(WebpValidationResult, is_valid),
+            offset_of!(WebpValidationResult, width),
+            offset_of!(WebpValidationResult, height),
+            offset_of!(WebpValidationResult, has_alpha),
+            offset_of!(WebpValidationResult, is_animated),
+            offset_of!(WebpValidationResult, num_frames),
+            offset_of!(WebpValidationResult, error_message),
+            offset_of!(WebpValidationResult, is_partial),
+            offset_of!(WebpValidationResult, features),
+        ];
+        assert!(offsets.windows(2).all(|pair| pair[0] < pair[1]));
+    }
+
+    #[test]
+    fn test_container_features() {
+        // VP8X declaring ICC and XMP; EXIF only present as a chunk.
+        let vp8x = [0x24, 0, 0, 0, 0, 0, 0, 0, 0, 0];
+        let data = container(&[(b"VP8X", &vp8x), (b"EXIF", b"II*\0"), (b"VP8L", b"\x2f")]);
+        assert_eq!(
+            container_features(&data),
+            features::ICC | features::XMP | features::EXIF | features::LOSSLESS
+        );
+
+        let data = container(&[
+            (b"VP8X", &[0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0]),
+            (b"FRGM", b""),
+        ]);
+        assert_eq!(container_features(&data), features::FRAGMENTS);
+        assert_eq!(container_features(b"not a webp"), 0);
+
+        let data = fixtures::animated();
+        let info = validate_webp(&data).expect("dynamic webp should be valid");
+        assert_ne!(info.features & features::ANIMATION, 0);
+        assert_eq!(info.features & !features::SUPPORTED, 0);
+        assert_eq!(webp_supported_features_ffi(), features::SUPPORTED);
+        let version = unsafe { std::ffi::CStr::from_ptr(webp_validator_version_ffi()) };
+        assert_eq!(version.to_str().unwrap(), env!("CARGO_PKG_VERSION"));
+
+        let partial = partial_webp_info(&data[..data.len() / 2]).expect("should recover metadata");
+        assert_ne!(partial.features & features::ANIMATION, 0);
+    }
+
     #[test]
     fn test_webp_info_debug() {
-        let data = fs::read("images/static.webp").expect("failed to read file");
+        let data = ALPHA.to_vec();
         let result = validate_webp(&data);
         assert!(result.is_ok());
 
@@ -0,0 +1,335 @@
+//! Best-effort RIFF container walking, independent of the decoder.
+//!
+//! Used to recover whatever metadata is readable from files the decoder
+//! rejects, and to locate structural problems by byte range.
+
+/// A chunk header found in the RIFF container
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct Chunk {
+    pub fourcc: [u8; 4],
+    /// Offset of the chunk header from the start of the file
+    pub offset: u64,
+    /// Payload size as declared in the chunk header (excluding padding)
+    pub size: u32,
+    /// 0 for top-level chunks, 1 for chunks nested in an ANMF frame
+    pub depth: u32,
+}
+
+impl Chunk {
+    /// Offset of the first payload byte
+    pub fn payload_offset(&self) -> u64 {
+        self.offset + 8
+    }
+
+    /// Offset just past the payload and its padding byte, if any
+    pub fn end(&self) -> u64 {
+        self.payload_offset() + self.size as u64 + (self.size & 1) as u64
+    }
+
+    /// Payload bytes, or None if the payload runs past the end of data
+    pub fn payload<'a>(&self, data: &'a [u8]) -> Option<&'a [u8]> {
+        let start = self.payload_offset() as usize;
+        let end = start.checked_add(self.size as usize)?;
+        data.get(start..end)
+    }
+}
+
+/// How serious a structural finding is
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Severity {
+    /// The container is malformed
+    Error,
+    /// The container is readable but not strictly conformant
+    Warning,
+}
+
+/// A structural problem located at a byte range of the file
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Finding {
+    pub offset: u64,
+    pub length: u64,
+    pub severity: Severity,
+    pub message: String,
+}
+
+/// Everything found while walking a RIFF container
+#[derive(Debug, Default)]
+pub struct Walk {
+    /// Complete chunks in file order, ANMF sub-chunks following their frame
+    pub chunks: Vec<Chunk>,
+    pub findings: Vec<Finding>,
+}
+
+impl Walk {
+    fn error(&mut self, offset: u64, length: u64, message: String) {
+        self.findings.push(Finding {
+            offset,
+            length,
+            severity: Severity::Error,
+            message,
+        });
+    }
+
+    fn warning(&mut self, offset: u64, length: u64, message: String) {
+        self.findings.push(Finding {
+            offset,
+            length,
+            severity: Severity::Warning,
+            message,
+        });
+    }
+}
+
+/// Printable form of a fourcc, escaping non-ASCII bytes
+pub fn fourcc_name(fourcc: &[u8; 4]) -> String {
+    fourcc.escape_ascii().to_string()
+}
+
+/// Whether data starts with a RIFF/WEBP file header
+pub fn has_webp_header(data: &[u8]) -> bool {
+    data.len() >= 12 && &data[0..4] == b"RIFF" && &data[8..12] == b"WEBP"
+}
+
+/// Walk the container, recording every complete chunk and every
+/// structural problem with the byte range it covers.
+///
+/// Walking stops at the first chunk whose payload is not fully contained
+/// in the container, so every returned chunk can be read safely.
+pub fn walk(data: &[u8]) -> Walk {
+    let mut walk = Walk::default();
+    let len = data.len() as u64;
+
+    if data.len() < 4 || &data[0..4] != b"RIFF" {
+        walk.error(0, len.min(4), "missing RIFF signature".to_string());
+        return walk;
+    }
+    if data.len() < 12 {
+        walk.error(0, len, "file too short for RIFF header".to_string());
+        return walk;
+    }
+    if &data[8..12] != b"WEBP" {
+        walk.error(8, 4, "missing WEBP signature".to_string());
+        return walk;
+    }
+
+    // Computed in u64: a declared size near u32::MAX must not wrap.
+    let declared = u32::from_le_bytes([data[4], data[5], data[6], data[7]]) as u64 + 8;
+    let end = if declared > len {
+        walk.error(
+            4,
+            4,
+            format!("riff size declares {} bytes, file has {}", declared, len),
+        );
+        len
+    } else {
+        if declared < len {
+            walk.warning(
+                declared,
+                len - declared,
+                "trailing data after riff container".to_string(),
+            );
+        }
+        declared
+    };
+
+    walk_chunks(data, 12, end, 0, &mut walk);
+    walk
+}
+
+fn walk_chunks(data: &[u8], mut offset: u64, end: u64, depth: u32, walk: &mut Walk) {
+    while offset < end {
+        if end - offset < 8 {
+            walk.error(offset, end - offset, "truncated chunk header".to_string());
+            return;
+        }
+
+        let header = &data[offset as usize..offset as usize + 8];
+        let chunk = Chunk {
+            fourcc: [header[0], header[1], header[2], header[3]],
+            offset,
+            size: u32::from_le_bytes([header[4], header[5], header[6], header[7]]),
+            depth,
+        };
+        let payload_end = chunk.payload_offset() + chunk.size as u64;
+        if payload_end > end {
+            walk.error(
+                offset,
+                end - offset,
+                format!(
+                    "{} chunk declares {} bytes, only {} available",
+                    fourcc_name(&chunk.fourcc),
+                    chunk.size,
+                    end - chunk.payload_offset()
+                ),
+            );
+            return;
+        }
+        walk.chunks.push(chunk);
+
+        if &chunk.fourcc == b"ANMF" {
+            if chunk.size < 16 {
+                walk.error(
+                    chunk.payload_offset(),
+                    chunk.size as u64,
+                    "ANMF frame header too short".to_string(),
+                );
+            } else {
+                walk_chunks(
+                    data,
+                    chunk.payload_offset() + 16,
+                    payload_end,
+                    depth + 1,
+                    walk,
+                );
+            }
+        }
+
+        if chunk.end() > end {
+            walk.warning(
+                payload_end,
+                0,
+                format!(
+                    "{} chunk is missing its padding byte",
+                    fourcc_name(&chunk.fourcc)
+                ),
+            );
+            return;
+        }
+        offset = chunk.end();
+    }
+}
+
+/// Top-level chunks following the file header.
+///
+/// Walking stops at the first chunk whose payload is not fully contained
+/// in data, so every returned chunk can be read safely.
+pub fn chunks(data: &[u8]) -> Vec<Chunk> {
+    walk(data)
+        .chunks
+        .into_iter()
+        .filter(|chunk| chunk.depth == 0)
+        .collect()
+}
+
+/// Read a 24-bit little-endian value
+pub fn read_u24(b: &[u8]) -> u32 {
+    b[0] as u32 | (b[1] as u32) << 8 | (b[2] as u32) << 16
+}
+
+/// Dimensions encoded in a VP8 key frame header
+pub fn vp8_dimensions(payload: &[u8]) -> Option<(u32, u32)> {
+    if payload.len() < 10 || payload[3..6] != [0x9d, 0x01, 0x2a] {
+        return None;
+    }
+    let width = u16::from_le_bytes([payload[6], payload[7]]) & 0x3fff;
+    let height = u16::from_le_bytes([payload[8], payload[9]]) & 0x3fff;
+    Some((width as u32, height as u32))
+}
+
+/// Dimensions and alpha hint encoded in a VP8L header
+pub fn vp8l_header(payload: &[u8]) -> Option<(u32, u32, bool)> {
+    if payload.len() < 5 || payload[0] != 0x2f {
+        return None;
+    }
+    let bits = u32::from_le_bytes([payload[1], payload[2], payload[3], payload[4]]);
+    let width = (bits & 0x3fff) + 1;
+    let height = ((bits >> 14) & 0x3fff) + 1;
+    let has_alpha = bits & (1 << 28) != 0;
+    Some((width, height, has_alpha))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::fixtures::{self, ALPHA, NOT_WEBP};
+
+    #[test]
+    fn test_chunks_static_webp() {
+        let data = ALPHA.to_vec();
+        let fourccs: Vec<[u8; 4]> = chunks(&data).iter().map(|c| c.fourcc).collect();
+
+        assert_eq!(fourccs, vec![*b"VP8X", *b"ALPH", *b"VP8 "]);
+    }
+
+    #[test]
+    fn test_chunks_stop_at_truncation() {
+        let data = ALPHA.to_vec();
+        let all = chunks(&data);
+        let last = all.last().unwrap();
+
+        let truncated = chunks(&data[..last.end() as usize - 1]);
+        assert_eq!(
+            truncated.len(),
+            all.len() - 1,
+            "incomplete chunk should be dropped"
+        );
+    }
+
+    #[test]
+    fn test_walk_nested_frames() {
+        let data = fixtures::animated();
+        let walk = walk(&data);
+
+        assert!(walk.findings.is_empty(), "findings: {:?}", walk.findings);
+        let frames = walk.chunks.iter().filter(|c| &c.fourcc == b"ANMF").count();
+        let nested = walk.chunks.iter().filter(|c| c.depth == 1).count();
+        assert!(frames > 1, "should find animation frames");
+        assert!(
+            nested >= frames,
+            "each frame should contain a bitstream chunk"
+        );
+    }
+
+    #[test]
+    fn test_walk_truncated_chunk_range() {
+        let data = ALPHA.to_vec();
+        let last = *chunks(&data).last().unwrap();
+        let walk = walk(&data[..data.len() - 10]);
+
+        let expected = vec![
+            Finding {
+                offset: 4,
+                length: 4,
+                severity: Severity::Error,
+                message: format!(
+                    "riff size declares {} bytes, file has {}",
+                    data.len(),
+                    data.len() - 10
+                ),
+            },
+            Finding {
+                offset: last.offset,
+                length: data.len() as u64 - 10 - last.offset,
+                severity: Severity::Error,
+                message: format!(
+                    "VP8  chunk declares {} bytes, only {} available",
+                    last.size,
+                    last.size - 10
+                ),
+            },
+        ];
+        assert_eq!(walk.findings, expected);
+    }
+
+    #[test]
+    fn test_walk_trailing_data() {
+        let mut data = ALPHA.to_vec();
+        let len = data.len() as u64;
+        data.extend_from_slice(b"garbage");
+
+        let walk = walk(&data);
+        assert_eq!(walk.findings.len(), 1);
+        assert_eq!(walk.findings[0].severity, Severity::Warning);
+        assert_eq!((walk.findings[0].offset, walk.findings[0].length), (len, 7));
+    }
+
+    #[test]
+    fn test_chunks_non_webp() {
+        let data = NOT_WEBP.to_vec();
+        assert_eq!(walk(&data).findings[0].offset, 0);
+        assert!(
+            chunks(&data).is_empty(),
+            "non-webp data should have no chunks"
+        );
+    }
+}